@@ -10,6 +10,8 @@ import (
 	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
 	"github.com/longhorn/go-spdk-helper/pkg/types"
 	"github.com/longhorn/go-spdk-helper/pkg/util"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
 )
 
 func DeviceCmd() cli.Command {
@@ -18,6 +20,7 @@ func DeviceCmd() cli.Command {
 		Subcommands: []cli.Command{
 			DeviceAddCmd(),
 			DeviceDeleteCmd(),
+			DeviceGrowCmd(),
 		},
 	}
 }
@@ -32,6 +35,14 @@ func DeviceAddCmd() cli.Command {
 				Usage: "Logical volume store cluster size, by default 1MiB",
 				Value: types.MiB,
 			},
+			cli.StringFlag{
+				Name:  "clear-method",
+				Usage: "How to clear the data of the base bdev on creation. Available: none, unmap, write_zeroes. By default unmap",
+			},
+			cli.UintFlag{
+				Name:  "md-pages-per-cluster-ratio",
+				Usage: "Reserved metadata pages per cluster. Increase it for many small lvols. By default 100",
+			},
 		},
 		Action: func(c *cli.Context) {
 			if err := deviceAdd(c); err != nil {
@@ -49,7 +60,12 @@ func deviceAdd(c *cli.Context) error {
 		return err
 	}
 
-	bdevAioName, lvsName, lvsUUID, err := spdkCli.AddDevice(devicePath, "", uint32(c.Uint("cluster-size")))
+	bdevAioName, lvsName, lvsUUID, err := spdkCli.AddDeviceWithOptions(client.AddDeviceOptions{
+		DevicePath:                devicePath,
+		ClusterSize:               uint32(c.Uint("cluster-size")),
+		ClearMethod:               spdktypes.BdevLvolClearMethod(c.String("clear-method")),
+		NumMdPagesPerClusterRatio: uint32(c.Uint("md-pages-per-cluster-ratio")),
+	})
 	if err != nil {
 		return err
 	}
@@ -88,3 +104,39 @@ func deviceDelete(c *cli.Context) error {
 
 	return util.PrintObject(true)
 }
+
+func DeviceGrowCmd() cli.Command {
+	return cli.Command{
+		Name:  "grow",
+		Usage: "Grow the aio bdev and the lvstore of a device after enlarging the device. The aio name and the lvs name should be the file device file name: grow <device path>",
+		Flags: []cli.Flag{
+			cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "How long to wait for SPDK to notice the new size of the device",
+				Value: client.DefaultDeviceGrowWaitTimeout,
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := deviceGrow(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run grow device command")
+			}
+		},
+	}
+}
+
+func deviceGrow(c *cli.Context) error {
+	devicePath := c.Args().First()
+	fileName := filepath.Base(devicePath)
+
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	info, err := spdkCli.GrowDevice(fileName, fileName, c.Duration("timeout"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(info)
+}
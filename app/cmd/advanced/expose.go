@@ -2,12 +2,17 @@ package advanced
 
 import (
 	"context"
+	"fmt"
+	"net"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 
 	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/types"
 	"github.com/longhorn/go-spdk-helper/pkg/util"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
 )
 
 func ExposeCmd() cli.Command {
@@ -16,6 +21,10 @@ func ExposeCmd() cli.Command {
 		Subcommands: []cli.Command{
 			StartExposeCmd(),
 			StopExposeCmd(),
+			StartExposeMultipathCmd(),
+			SetOptimizedListenerCmd(),
+			StartDiscoveryCmd(),
+			StopDiscoveryCmd(),
 		},
 	}
 }
@@ -103,3 +112,202 @@ func stopExpose(c *cli.Context) error {
 
 	return util.PrintObject(true)
 }
+
+func StartExposeMultipathCmd() cli.Command {
+	return cli.Command{
+		Name:  "start-multipath",
+		Usage: "Expose a bdev via nvmf on multiple listeners with ANA reporting: start-multipath --nqn <NVMF SUBSYSTEM NQN> --bdev-name <BDEV ALIAS or BDEV UUID> --listener <IP>:<PORT> --listener <IP>:<PORT> [--optimized-listener <IP>:<PORT>]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "nqn",
+				Usage:    "NVMe-oF target subsystem NQN",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "bdev-name",
+				Usage:    "Name of the exported bdev lvol",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "nguid",
+				Usage:    "Namespace globally unique identifier",
+				Required: false,
+			},
+			cli.StringSliceFlag{
+				Name:     "listener",
+				Usage:    "Listen address in the format of <IP>:<PORT>. Specify this multiple times for multiple listeners",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "optimized-listener",
+				Usage: "The only optimized listen address in the format of <IP>:<PORT>, the others are inaccessible. All listeners are optimized if it is not specified",
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := startExposeMultipath(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run start multipath expose command")
+			}
+		},
+	}
+}
+
+func startExposeMultipath(c *cli.Context) error {
+	var optimizedIP, optimizedPort string
+	if optimized := c.String("optimized-listener"); optimized != "" {
+		var err error
+		if optimizedIP, optimizedPort, err = net.SplitHostPort(optimized); err != nil {
+			return err
+		}
+	}
+
+	listeners := []client.ExposeListener{}
+	optimizedFound := false
+	for _, address := range c.StringSlice("listener") {
+		ip, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		listener := client.ExposeListener{
+			IP:   ip,
+			Port: port,
+		}
+		if optimizedIP != "" {
+			if types.NormalizeIP(ip) == types.NormalizeIP(optimizedIP) && port == optimizedPort {
+				optimizedFound = true
+			} else {
+				listener.AnaState = spdktypes.NvmfSubsystemListenerAnaStateInaccessible
+			}
+		}
+		listeners = append(listeners, listener)
+	}
+	if optimizedIP != "" && !optimizedFound {
+		return fmt.Errorf("optimized listener %s is not one of the listeners %v", c.String("optimized-listener"), c.StringSlice("listener"))
+	}
+
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := spdkCli.StartExposeBdevMultipath(c.String("nqn"), c.String("bdev-name"), c.String("nguid"), listeners); err != nil {
+		return err
+	}
+
+	return util.PrintObject(true)
+}
+
+func SetOptimizedListenerCmd() cli.Command {
+	return cli.Command{
+		Name:  "set-optimized-listener",
+		Usage: "Steer the multipath hosts to a listener by making it the only optimized one: set-optimized-listener --nqn <NVMF SUBSYSTEM NQN> --ip <IP ADDRESS> --port <PORT NUMBER>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "nqn",
+				Usage:    "NVMe-oF target subsystem NQN",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "ip",
+				Usage:    "IP address of the listener",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "port",
+				Usage:    "Port number of the listener",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := setOptimizedListener(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run set optimized listener command")
+			}
+		},
+	}
+}
+
+func setOptimizedListener(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := spdkCli.SetExposeBdevOptimizedListener(c.String("nqn"), c.String("ip"), c.String("port")); err != nil {
+		return err
+	}
+
+	return util.PrintObject(true)
+}
+
+func StartDiscoveryCmd() cli.Command {
+	return cli.Command{
+		Name:  "discovery-start",
+		Usage: "Make the discovery service listing all exposed subsystems listen on an address: discovery-start --ip <IP ADDRESS> --port <PORT NUMBER>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "ip",
+				Usage:    "This can be host IP or localhost IP",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "port",
+				Usage: "Port number",
+				Value: "8009",
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := startDiscovery(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run start discovery command")
+			}
+		},
+	}
+}
+
+func startDiscovery(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := spdkCli.StartDiscoveryService(c.String("ip"), c.String("port")); err != nil {
+		return err
+	}
+
+	return util.PrintObject(true)
+}
+
+func StopDiscoveryCmd() cli.Command {
+	return cli.Command{
+		Name:  "discovery-stop",
+		Usage: "Stop the discovery service listening on an address: discovery-stop --ip <IP ADDRESS> --port <PORT NUMBER>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "ip",
+				Usage:    "This can be host IP or localhost IP",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "port",
+				Usage: "Port number",
+				Value: "8009",
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := stopDiscovery(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run stop discovery command")
+			}
+		},
+	}
+}
+
+func stopDiscovery(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := spdkCli.StopDiscoveryService(c.String("ip"), c.String("port")); err != nil {
+		return err
+	}
+
+	return util.PrintObject(true)
+}
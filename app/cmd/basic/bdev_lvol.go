@@ -3,15 +3,21 @@ package basic
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 
 	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
-	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/scrub"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/shallowcopy"
+	"github.com/longhorn/go-spdk-helper/pkg/types"
 	"github.com/longhorn/go-spdk-helper/pkg/util"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
 )
 
 func BdevLvolCmd() cli.Command {
@@ -25,10 +31,14 @@ func BdevLvolCmd() cli.Command {
 			BdevLvolSnapshotCmd(),
 			BdevLvolCloneCmd(),
 			BdevLvolCloneBdevCmd(),
+			BdevLvolCreateEsnapCloneCmd(),
 			BdevLvolSetParentCmd(),
 			BdevLvolDecoupleParentCmd(),
 			BdevLvolDetachParentCmd(),
 			BdevLvolResizeCmd(),
+			BdevLvolSetReadOnlyCmd(),
+			BdevLvolInflateCmd(),
+			BdevLvolShallowCopyCmd(),
 			BdevLvolStartShallowCopyCmd(),
 			BdevLvolCheckShallowCopyCmd(),
 			BdevLvolSetXattrCmd(),
@@ -38,6 +48,7 @@ func BdevLvolCmd() cli.Command {
 			BdevLvolRegisterSnapshotChecksumCmd(),
 			BdevLvolGetSnapshotChecksumCmd(),
 			BdevLvolStopSnapshotChecksumCmd(),
+			BdevLvolScrubCmd(),
 		},
 	}
 }
@@ -63,6 +74,23 @@ func BdevLvolCreateCmd() cli.Command {
 				Usage:    "Specify bdev lvol size in MiB",
 				Required: true,
 			},
+			cli.StringFlag{
+				Name:  "uuid",
+				Usage: "The UUID of the lvol, which is used to recreate a lvol with its original UUID. Optional",
+			},
+			cli.StringFlag{
+				Name:  "clear-method",
+				Usage: "How to clear the data clusters of the lvol. Available: none, unmap, write_zeroes",
+				Value: spdktypes.BdevLvolClearMethodUnmap,
+			},
+			cli.BoolTFlag{
+				Name:  "thin-provision",
+				Usage: "Create a thin provisioned lvol. Set this to false for a thick provisioned one",
+			},
+			cli.StringSliceFlag{
+				Name:  "xattr",
+				Usage: "Xattr for the lvol in the format name=value. Optional",
+			},
 		},
 		Usage: "create a bdev lvol on a lvstore: \"create --lvs-name <LVSTORE NAME> --lvol-name <LVOL NAME> --size <LVOL SIZE in MIB>\"",
 		Action: func(c *cli.Context) {
@@ -81,10 +109,22 @@ func bdevLvolCreate(c *cli.Context) error {
 
 	lvsName, lvsUUID := c.String("lvs-name"), c.String("lvs-uuid")
 	lvolName := c.String("lvol-name")
-	size := c.Uint64("size")
 
-	uuid, err := spdkCli.BdevLvolCreate(lvsName, lvsUUID, lvolName, size,
-		spdktypes.BdevLvolClearMethodUnmap, true)
+	xattrs, err := parseXattrs(c.StringSlice("xattr"))
+	if err != nil {
+		return err
+	}
+
+	uuid, err := spdkCli.BdevLvolCreateWithOptions(client.BdevLvolCreateOptions{
+		LvstoreName:   lvsName,
+		LvstoreUUID:   lvsUUID,
+		LvolName:      lvolName,
+		LvolUUID:      c.String("uuid"),
+		SizeInMib:     c.Uint64("size"),
+		ClearMethod:   spdktypes.BdevLvolClearMethod(c.String("clear-method")),
+		ThinProvision: c.BoolT("thin-provision"),
+		Xattrs:        xattrs,
+	})
 	if err != nil {
 		return err
 	}
@@ -208,12 +248,24 @@ func bdevLvolSnapshot(c *cli.Context) error {
 		name = c.String("uuid")
 	}
 
-	var xattrs []client.Xattr
-	xattrs_args := c.StringSlice("xattr")
-	for _, s := range xattrs_args {
+	xattrs, err := parseXattrs(c.StringSlice("xattr"))
+	if err != nil {
+		return err
+	}
+
+	uuid, err := spdkCli.BdevLvolSnapshot(name, c.String("snapshot-name"), xattrs)
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(uuid)
+}
+
+func parseXattrs(xattrsArgs []string) (xattrs []client.Xattr, err error) {
+	for _, s := range xattrsArgs {
 		parts := strings.Split(s, "=")
 		if len(parts) != 2 {
-			return errors.Errorf("xattr %q not in name=value format", s)
+			return nil, errors.Errorf("xattr %q not in name=value format", s)
 		}
 
 		xattr := client.Xattr{
@@ -223,12 +275,7 @@ func bdevLvolSnapshot(c *cli.Context) error {
 		xattrs = append(xattrs, xattr)
 	}
 
-	uuid, err := spdkCli.BdevLvolSnapshot(name, c.String("snapshot-name"), xattrs)
-	if err != nil {
-		return err
-	}
-
-	return util.PrintObject(uuid)
+	return xattrs, nil
 }
 
 func BdevLvolCloneCmd() cli.Command {
@@ -309,6 +356,52 @@ func bdevLvolCloneBdev(c *cli.Context) error {
 	return util.PrintObject(uuid)
 }
 
+func BdevLvolCreateEsnapCloneCmd() cli.Command {
+	return cli.Command{
+		Name: "create-esnap-clone",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "esnap-uuid",
+				Usage:    "UUID of the bdev that acts as the external snapshot",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "lvs-name",
+				Usage: "Specify this or lvs-uuid",
+			},
+			cli.StringFlag{
+				Name:  "lvs-uuid",
+				Usage: "Specify this or lvs-name",
+			},
+			cli.StringFlag{
+				Name:     "clone-name",
+				Usage:    "Name for the logical volume to create",
+				Required: true,
+			},
+		},
+		Usage: "create a lvol based on an external snapshot bdev: \"create-esnap-clone --esnap-uuid <BDEV UUID> --lvs-name <LVSTORE NAME> --clone-name <CLONE NAME>\"",
+		Action: func(c *cli.Context) {
+			if err := bdevLvolCreateEsnapClone(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run create esnap clone command")
+			}
+		},
+	}
+}
+
+func bdevLvolCreateEsnapClone(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	uuid, err := spdkCli.BdevLvolCreateEsnapClone(c.String("esnap-uuid"), c.String("lvs-name"), c.String("lvs-uuid"), c.String("clone-name"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(uuid)
+}
+
 func BdevLvolDecoupleParentCmd() cli.Command {
 	return cli.Command{
 		Name: "decouple",
@@ -474,6 +567,154 @@ func bdevLvolResize(c *cli.Context) error {
 	return util.PrintObject(resized)
 }
 
+func BdevLvolSetReadOnlyCmd() cli.Command {
+	return cli.Command{
+		Name: "set-read-only",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "alias",
+				Usage: "The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>. Specify this or uuid",
+			},
+			cli.StringFlag{
+				Name:  "uuid",
+				Usage: "Specify this or alias",
+			},
+		},
+		Usage: "mark a lvol as read only: \"set-read-only --alias <LVSTORE NAME>/<LVOL NAME>\", or \"set-read-only --uuid <LVOL UUID>\"",
+		Action: func(c *cli.Context) {
+			if err := bdevLvolSetReadOnly(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run set read only bdev lvol command")
+			}
+		},
+	}
+}
+
+func bdevLvolSetReadOnly(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	name := c.String("alias")
+	if name == "" {
+		name = c.String("uuid")
+	}
+
+	set, err := spdkCli.BdevLvolSetReadOnly(name)
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(set)
+}
+
+func BdevLvolInflateCmd() cli.Command {
+	return cli.Command{
+		Name: "inflate",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "alias",
+				Usage: "The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>. Specify this or uuid",
+			},
+			cli.StringFlag{
+				Name:  "uuid",
+				Usage: "Specify this or alias",
+			},
+		},
+		Usage: "allocate all clusters of a lvol and remove its dependency on the parent: \"inflate --alias <LVSTORE NAME>/<LVOL NAME>\", or \"inflate --uuid <LVOL UUID>\"",
+		Action: func(c *cli.Context) {
+			if err := bdevLvolInflate(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run inflate bdev lvol command")
+			}
+		},
+	}
+}
+
+func bdevLvolInflate(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	name := c.String("alias")
+	if name == "" {
+		name = c.String("uuid")
+	}
+
+	inflated, err := spdkCli.BdevLvolInflate(name)
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(inflated)
+}
+
+func BdevLvolShallowCopyCmd() cli.Command {
+	return cli.Command{
+		Name: "shallow-copy",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "src-lvol-alias",
+				Usage: "The alias of a snapshot lvol to create a copy from, which is <LVSTORE NAME>/<LVOL NAME>. Specify this or uuid",
+			},
+			cli.StringFlag{
+				Name:  "src-lvol-uuid",
+				Usage: "Specify this or alias",
+			},
+			cli.StringFlag{
+				Name:     "dst-bdev-name",
+				Usage:    "Name of the bdev that acts as destination for the copy",
+				Required: true,
+			},
+			cli.DurationFlag{
+				Name:  "poll-interval",
+				Usage: "The interval of checking the copy status",
+				Value: shallowcopy.DefaultPollInterval,
+			},
+		},
+		Usage: "copy active clusters/data from a read-only logical volume to a bdev, and wait for the copy to finish while showing the progress: " +
+			"\"shallow-copy --src-lvol-alias <LVSTORE NAME>/<LVOL NAME> --dst-bdev-name <BDEV NAME>\". Use shallow-copy-start to return without waiting",
+		Action: func(c *cli.Context) {
+			if err := bdevLvolShallowCopy(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run shallow copy bdev lvol command")
+			}
+		},
+	}
+}
+
+func bdevLvolShallowCopy(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	srcLvolName := c.String("src-lvol-alias")
+	if srcLvolName == "" {
+		srcLvolName = c.String("src-lvol-uuid")
+	}
+
+	manager, err := shallowcopy.NewManager(spdkCli, c.Duration("poll-interval"), -1)
+	if err != nil {
+		return err
+	}
+	job, err := manager.Start(context.Background(), srcLvolName, c.String("dst-bdev-name"))
+	if err != nil {
+		return err
+	}
+
+	for progress := range job.Progress() {
+		util.PrintProgressBar(progress.Percentage(), fmt.Sprintf("%d/%d clusters %.1f MiB/s ETA %v",
+			progress.CopiedClusters, progress.TotalClusters,
+			float64(progress.BytesPerSecond)/types.MiB, progress.ETA.Round(time.Second)))
+	}
+	fmt.Fprintln(os.Stderr)
+	if err := job.Wait(); err != nil {
+		return err
+	}
+
+	return util.PrintObject(job.Status())
+}
+
 func BdevLvolStartShallowCopyCmd() cli.Command {
 	return cli.Command{
 		Name: "shallow-copy-start",
@@ -887,3 +1128,54 @@ func bdevLvolStopSnapshotChecksum(c *cli.Context) error {
 
 	return util.PrintObject(registered)
 }
+
+func BdevLvolScrubCmd() cli.Command {
+	return cli.Command{
+		Name: "scrub",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "lvs-name",
+				Usage:    "Name of the logical volume store whose snapshots are scrubbed",
+				Required: true,
+			},
+			cli.DurationFlag{
+				Name:  "interval",
+				Usage: "The pause between scrubbing two snapshots",
+				Value: scrub.DefaultInterval,
+			},
+		},
+		Usage: "compute the checksums of all snapshots in a lvstore and compare them with the ones stored by previous scrubs: \"scrub --lvs-name <LVSTORE NAME>\". " +
+			"The expected checksums are kept in the thin provisioned lvol \"" + scrub.StateLvolName + "\", which the first scrub creates in the lvstore",
+		Action: func(c *cli.Context) {
+			if err := bdevLvolScrub(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run scrub bdev lvol command")
+			}
+		},
+	}
+}
+
+func bdevLvolScrub(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	scrubber, err := scrub.NewScrubber(spdkCli, c.String("lvs-name"), c.Duration("interval"))
+	if err != nil {
+		return err
+	}
+
+	eventCh := make(chan scrub.Result)
+	go func() {
+		for result := range eventCh {
+			logrus.Infof("Scrubbed snapshot %s: %s", result.Name, result.State)
+		}
+	}()
+	report, err := scrubber.Scrub(context.Background(), eventCh)
+	close(eventCh)
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(report)
+}
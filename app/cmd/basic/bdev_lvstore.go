@@ -7,8 +7,11 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/monitor"
 	"github.com/longhorn/go-spdk-helper/pkg/types"
 	"github.com/longhorn/go-spdk-helper/pkg/util"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
 )
 
 func BdevLvstoreCmd() cli.Command {
@@ -20,7 +23,10 @@ func BdevLvstoreCmd() cli.Command {
 			BdevLvstoreDeleteCmd(),
 			BdevLvstoreGetCmd(),
 			BdevLvstoreRenameCmd(),
+			BdevLvstoreGrowCmd(),
 			BdevLvstoreGetLvolsCmd(),
+			BdevLvstoreUsageCmd(),
+			BdevLvstoreWatchCmd(),
 		},
 	}
 }
@@ -44,6 +50,14 @@ func BdevLvstoreCreateCmd() cli.Command {
 				Usage: "Logical volume store cluster size, by default 1MiB",
 				Value: types.MiB,
 			},
+			cli.StringFlag{
+				Name:  "clear-method",
+				Usage: "How to clear the data of the base bdev on creation. Available: none, unmap, write_zeroes. By default unmap",
+			},
+			cli.UintFlag{
+				Name:  "md-pages-per-cluster-ratio",
+				Usage: "Reserved metadata pages per cluster. Increase it for many small lvols. By default 100",
+			},
 		},
 		Usage: "create a bdev lvstore based on a block device: \"create --bdev-name <BDEV NAME> --lvs-name <LVSTORE NAME>\"",
 		Action: func(c *cli.Context) {
@@ -60,7 +74,13 @@ func bdevLvstoreCreate(c *cli.Context) error {
 		return err
 	}
 
-	uuid, err := spdkCli.BdevLvolCreateLvstore(c.String("bdev-name"), c.String("lvs-name"), uint32(c.Uint("cluster-size")))
+	uuid, err := spdkCli.BdevLvolCreateLvstoreWithOptions(client.BdevLvolCreateLvstoreOptions{
+		BdevName:                  c.String("bdev-name"),
+		LvsName:                   c.String("lvs-name"),
+		ClusterSize:               uint32(c.Uint("cluster-size")),
+		ClearMethod:               spdktypes.BdevLvolClearMethod(c.String("clear-method")),
+		NumMdPagesPerClusterRatio: uint32(c.Uint("md-pages-per-cluster-ratio")),
+	})
 	if err != nil {
 		return err
 	}
@@ -106,6 +126,42 @@ func bdevLvstoreRename(c *cli.Context) error {
 	return util.PrintObject(renamed)
 }
 
+func BdevLvstoreGrowCmd() cli.Command {
+	return cli.Command{
+		Name: "grow",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "lvs-name",
+				Usage: "Specify this or uuid",
+			},
+			cli.StringFlag{
+				Name:  "uuid",
+				Usage: "Specify this or lvs-name",
+			},
+		},
+		Usage: "grow a bdev lvstore to fill the extra space of its base bdev: \"grow --lvs-name <LVSTORE NAME>\" or \"grow --uuid <UUID>\"",
+		Action: func(c *cli.Context) {
+			if err := bdevLvstoreGrow(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run grow bdev lvstore command")
+			}
+		},
+	}
+}
+
+func bdevLvstoreGrow(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	grown, err := spdkCli.BdevLvolGrowLvstore(c.String("lvs-name"), c.String("uuid"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(grown)
+}
+
 func BdevLvstoreDeleteCmd() cli.Command {
 	return cli.Command{
 		Name: "delete",
@@ -190,6 +246,18 @@ func BdevLvstoreGetLvolsCmd() cli.Command {
 				Name:  "uuid",
 				Usage: "If you want to get one specific Lvstore info, please input this or lvs-name",
 			},
+			cli.BoolFlag{
+				Name:  "snapshots-only",
+				Usage: "List snapshots only",
+			},
+			cli.BoolFlag{
+				Name:  "clones-only",
+				Usage: "List clones of snapshots or external snapshots only",
+			},
+			cli.BoolFlag{
+				Name:  "degraded-only",
+				Usage: "List degraded lvols only",
+			},
 		},
 		Usage: "list all logical volumes info: \"list\", or \"list --lvs-name <LVSTORE NAME>\", or \"list --uuid <LVSTORE UUID>\"",
 		Action: func(c *cli.Context) {
@@ -206,10 +274,141 @@ func bdevLvolList(c *cli.Context) error {
 		return err
 	}
 
-	bdevLvstoreGetResp, err := spdkCli.BdevLvolGetLvols(c.String("lvs-name"), c.String("uuid"))
+	snapshotsOnly, clonesOnly, degradedOnly := c.Bool("snapshots-only"), c.Bool("clones-only"), c.Bool("degraded-only")
+	bdevLvstoreGetResp, err := spdkCli.BdevLvolGetLvolsWithFilter(c.String("lvs-name"), c.String("uuid"), func(lvol *spdktypes.LvolInfo) bool {
+		if snapshotsOnly && !lvol.IsSnapshot {
+			return false
+		}
+		if clonesOnly && !lvol.IsClone && !lvol.IsEsnapClone {
+			return false
+		}
+		if degradedOnly && !lvol.IsDegraded {
+			return false
+		}
+		return true
+	})
 	if err != nil {
 		return err
 	}
 
 	return util.PrintObject(bdevLvstoreGetResp)
 }
+
+func BdevLvstoreUsageCmd() cli.Command {
+	return cli.Command{
+		Name: "usage",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "lvs-name",
+				Usage: "If you want to get the usage of one specific Lvstore, please input this or uuid",
+			},
+			cli.StringFlag{
+				Name:  "uuid",
+				Usage: "If you want to get the usage of one specific Lvstore, please input this or lvs-name",
+			},
+			cli.IntFlag{
+				Name:  "top",
+				Usage: "The number of lvols allocating the most space to show. 0 means all",
+				Value: 5,
+			},
+		},
+		Usage: "report the provisioned and allocated space of all bdev lvstores if the info is not specified: \"usage\", or \"usage --lvs-name <LVSTORE NAME>\", or \"usage --uuid <UUID>\"",
+		Action: func(c *cli.Context) {
+			if err := bdevLvstoreUsage(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run usage bdev lvstore command")
+			}
+		},
+	}
+}
+
+func bdevLvstoreUsage(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	usageList, err := spdkCli.GetLvstoreUsage(c.String("lvs-name"), c.String("uuid"), c.Int("top"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(usageList)
+}
+
+func BdevLvstoreWatchCmd() cli.Command {
+	return cli.Command{
+		Name: "watch",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "lvs-name",
+				Usage: "If you want to watch one specific Lvstore, please input this",
+			},
+			cli.DurationFlag{
+				Name:  "interval",
+				Usage: "The interval of polling the lvstores",
+				Value: monitor.DefaultLvstoreWatchInterval,
+			},
+			cli.Float64Flag{
+				Name:  "soft-watermark",
+				Usage: "The free space percentage below which a soft watermark event is emitted",
+				Value: monitor.DefaultSoftWatermarkPercent,
+			},
+			cli.Float64Flag{
+				Name:  "hard-watermark",
+				Usage: "The free space percentage below which a hard watermark event is emitted",
+				Value: monitor.DefaultHardWatermarkPercent,
+			},
+			cli.Float64Flag{
+				Name:  "hysteresis",
+				Usage: "How many percents the free space must rise above a watermark before the level is lowered again",
+				Value: monitor.DefaultWatermarkHysteresis,
+			},
+			cli.StringFlag{
+				Name:  "webhook",
+				Usage: "The URL to post the events to as JSON. Optional",
+			},
+		},
+		Usage: "watch the free space of bdev lvstores and print an event when it crosses a watermark: \"watch\", or \"watch --lvs-name <LVSTORE NAME>\"",
+		Action: func(c *cli.Context) {
+			if err := bdevLvstoreWatch(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run watch bdev lvstore command")
+			}
+		},
+	}
+}
+
+func bdevLvstoreWatch(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	opts := monitor.LvstoreWatcherOptions{
+		LvsName:              c.String("lvs-name"),
+		Interval:             c.Duration("interval"),
+		SoftWatermarkPercent: c.Float64("soft-watermark"),
+		HardWatermarkPercent: c.Float64("hard-watermark"),
+	}
+	hysteresis := c.Float64("hysteresis")
+	opts.HysteresisPercent = &hysteresis
+	if webhook := c.String("webhook"); webhook != "" {
+		opts.Callback = monitor.NewWebhookCallback(webhook, 0)
+	}
+
+	watcher, err := monitor.NewLvstoreWatcher(spdkCli, opts)
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- watcher.Run(context.Background())
+	}()
+	for event := range watcher.Events() {
+		if err := util.PrintObject(event); err != nil {
+			return err
+		}
+	}
+
+	return <-errCh
+}
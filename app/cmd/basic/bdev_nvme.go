@@ -20,6 +20,12 @@ func BdevNvmeCmd() cli.Command {
 			BdevNvmeAttachControllerCmd(),
 			BdevNvmeDetachControllerCmd(),
 			BdevNvmeGetControllersCmd(),
+			BdevNvmeGetControllerHealthInfoCmd(),
+			BdevNvmeGetTransportStatisticsCmd(),
+			BdevNvmeSetMultipathPolicyCmd(),
+			BdevNvmeSetPreferredPathCmd(),
+			BdevNvmeGetIoPathsCmd(),
+			BdevNvmeSwitchActivePathCmd(),
 			BdevNvmeGetCmd(),
 			BdevNvmeSetOptionsCmd(),
 		},
@@ -56,8 +62,7 @@ func BdevNvmeAttachControllerCmd() cli.Command {
 			},
 			cli.StringFlag{
 				Name:  "adrfam",
-				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\"",
-				Value: string(spdktypes.NvmeAddressFamilyIPv4),
+				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\". Inferred from traddr if not specified",
 			},
 			cli.IntFlag{
 				Name:  "ctrlr-loss-timeout-sec",
@@ -84,6 +89,22 @@ func BdevNvmeAttachControllerCmd() cli.Command {
 				Usage: "Multipathing behavior: disable, failover, multipath. Default is failover",
 				Value: string(spdktypes.NvmeMultipathBehaviorFailover),
 			},
+			cli.StringFlag{
+				Name:  "hostnqn",
+				Usage: "The host NQN presented to the target. Optional",
+			},
+			cli.StringFlag{
+				Name:  "psk",
+				Usage: "Name of the keyring key used as the TLS pre-shared key. It enables TLS for tcp. Optional",
+			},
+			cli.StringFlag{
+				Name:  "dhchap-key",
+				Usage: "Name of the keyring key used to authenticate the host via DH-HMAC-CHAP. Optional",
+			},
+			cli.StringFlag{
+				Name:  "dhchap-ctrlr-key",
+				Usage: "Name of the keyring key used to authenticate the controller. Optional",
+			},
 		},
 		Action: func(c *cli.Context) {
 			if err := bdevNvmeAttachController(c); err != nil {
@@ -99,11 +120,22 @@ func bdevNvmeAttachController(c *cli.Context) error {
 		return err
 	}
 
-	bdevNameList, err := spdkCli.BdevNvmeAttachController(c.String("name"), c.String("subnqn"),
-		c.String("traddr"), c.String("trsvcid"),
-		spdktypes.NvmeTransportType(c.String("trtype")), spdktypes.NvmeAddressFamily(c.String("adrfam")),
-		int32(c.Int("ctrlr-loss-timeout-sec")), int32(c.Int("reconnect-delay-sec")), int32(c.Int("fast-io-fail-timeout-sec")),
-		c.String("multipath"))
+	bdevNameList, err := spdkCli.BdevNvmeAttachControllerWithOptions(client.BdevNvmeAttachControllerOptions{
+		Name:                 c.String("name"),
+		Subnqn:               c.String("subnqn"),
+		Traddr:               c.String("traddr"),
+		Trsvcid:              c.String("trsvcid"),
+		Trtype:               spdktypes.NvmeTransportType(c.String("trtype")),
+		Adrfam:               spdktypes.NvmeAddressFamily(c.String("adrfam")),
+		CtrlrLossTimeoutSec:  int32(c.Int("ctrlr-loss-timeout-sec")),
+		ReconnectDelaySec:    int32(c.Int("reconnect-delay-sec")),
+		FastIOFailTimeoutSec: int32(c.Int("fast-io-fail-timeout-sec")),
+		Multipath:            c.String("multipath"),
+		Hostnqn:              c.String("hostnqn"),
+		Psk:                  c.String("psk"),
+		DhchapKey:            c.String("dhchap-key"),
+		DhchapCtrlrKey:       c.String("dhchap-ctrlr-key"),
+	})
 	if err != nil {
 		return err
 	}
@@ -163,6 +195,192 @@ func bdevNvmeGetControllers(c *cli.Context) error {
 	return util.PrintObject(bdevNvmeGetControllersResp)
 }
 
+func BdevNvmeGetControllerHealthInfoCmd() cli.Command {
+	return cli.Command{
+		Name:  "controller-health-info",
+		Usage: "get the SMART / health information of a nvme controller: controller-health-info <CONTROLLER NAME>",
+		Action: func(c *cli.Context) {
+			if err := bdevNvmeGetControllerHealthInfo(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run get nvme controller health info command")
+			}
+		},
+	}
+}
+
+func bdevNvmeGetControllerHealthInfo(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	healthInfo, err := spdkCli.BdevNvmeGetControllerHealthInfo(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(healthInfo)
+}
+
+func BdevNvmeGetTransportStatisticsCmd() cli.Command {
+	return cli.Command{
+		Name:  "transport-statistics",
+		Usage: "get the transport statistics of all nvme poll groups: transport-statistics",
+		Action: func(c *cli.Context) {
+			if err := bdevNvmeGetTransportStatistics(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run get nvme transport statistics command")
+			}
+		},
+	}
+}
+
+func bdevNvmeGetTransportStatistics(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	stats, err := spdkCli.BdevNvmeGetTransportStatistics()
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(stats)
+}
+
+func BdevNvmeSetMultipathPolicyCmd() cli.Command {
+	return cli.Command{
+		Name:  "multipath-policy-set",
+		Usage: "set the multipath policy of a nvme bdev: multipath-policy-set --policy <POLICY> <NVME BDEV NAME>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "policy",
+				Usage:    "Multipath policy: \"active_passive\" or \"active_active\"",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "selector",
+				Usage: "Path selector of \"active_active\": \"round_robin\" or \"queue_depth\". Optional",
+			},
+			cli.UintFlag{
+				Name:  "rr-min-io",
+				Usage: "Number of I/Os routed to a path before switching to the next one for \"round_robin\". Optional",
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := bdevNvmeSetMultipathPolicy(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run set nvme multipath policy command")
+			}
+		},
+	}
+}
+
+func bdevNvmeSetMultipathPolicy(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	set, err := spdkCli.BdevNvmeSetMultipathPolicy(c.Args().First(), spdktypes.BdevNvmeMultipathPolicy(c.String("policy")),
+		spdktypes.BdevNvmeMultipathSelector(c.String("selector")), uint32(c.Uint("rr-min-io")))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(set)
+}
+
+func BdevNvmeSetPreferredPathCmd() cli.Command {
+	return cli.Command{
+		Name:  "preferred-path-set",
+		Usage: "set the preferred path of a nvme bdev: preferred-path-set --cntlid <CONTROLLER ID> <NVME BDEV NAME>",
+		Flags: []cli.Flag{
+			cli.UintFlag{
+				Name:     "cntlid",
+				Usage:    "NVMe-oF controller ID of the preferred path",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := bdevNvmeSetPreferredPath(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run set nvme preferred path command")
+			}
+		},
+	}
+}
+
+func bdevNvmeSetPreferredPath(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	set, err := spdkCli.BdevNvmeSetPreferredPath(c.Args().First(), uint16(c.Uint("cntlid")))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(set)
+}
+
+func BdevNvmeGetIoPathsCmd() cli.Command {
+	return cli.Command{
+		Name:  "io-path-get",
+		Usage: "get the io paths of all nvme bdevs if the name is not specified: io-path-get <NVME BDEV NAME>",
+		Action: func(c *cli.Context) {
+			if err := bdevNvmeGetIoPaths(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run get nvme io paths command")
+			}
+		},
+	}
+}
+
+func bdevNvmeGetIoPaths(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	ioPaths, err := spdkCli.BdevNvmeGetIoPaths(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(ioPaths)
+}
+
+func BdevNvmeSwitchActivePathCmd() cli.Command {
+	return cli.Command{
+		Name:  "active-path-switch",
+		Usage: "make the path to the given target the active one of a nvme bdev: active-path-switch --traddr <IP ADDRESS> --trsvcid <PORT NUMBER> <NVME BDEV NAME>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "traddr",
+				Usage:    "NVMe-oF target address of the new active path",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "trsvcid",
+				Usage:    "NVMe-oF target port number of the new active path",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := bdevNvmeSwitchActivePath(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run switch nvme active path command")
+			}
+		},
+	}
+}
+
+func bdevNvmeSwitchActivePath(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return spdkCli.SwitchBdevNvmeActivePath(c.Args().First(), c.String("traddr"), c.String("trsvcid"))
+}
+
 func BdevNvmeGetCmd() cli.Command {
 	return cli.Command{
 		Name: "get",
@@ -2,11 +2,14 @@ package basic
 
 import (
 	"context"
+	"fmt"
+	"os"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 
 	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/monitor"
 	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
 	"github.com/longhorn/go-spdk-helper/pkg/util"
 )
@@ -21,6 +24,9 @@ func BdevRaidCmd() cli.Command {
 			BdevRaidGetCmd(),
 			BdevRaidRemoveBaseBdevCmd(),
 			BdevRaidGrowBaseBdevCmd(),
+			BdevRaidAddBaseBdevCmd(),
+			BdevRaidReplaceBaseBdevCmd(),
+			BdevRaidWatchCmd(),
 		},
 	}
 }
@@ -50,6 +56,18 @@ func BdevRaidCreateCmd() cli.Command {
 				Usage:    "Names of Nvme bdevs, the input is like \"--base-devs Nvme0n1 --base-devs Nvme1n1\"",
 				Required: true,
 			},
+			cli.StringFlag{
+				Name:  "uuid",
+				Usage: "UUID of the raid bdev. Optional",
+			},
+			cli.BoolFlag{
+				Name:  "superblock",
+				Usage: "Store the raid configuration on the base bdevs so that the raid is reassembled after spdk_tgt restarts",
+			},
+			cli.BoolFlag{
+				Name:  "delta-bitmap",
+				Usage: "Track the regions written while a base bdev is missing so that only those regions are resynced",
+			},
 		},
 		Action: func(c *cli.Context) {
 			if err := bdevRaidCreate(c); err != nil {
@@ -65,7 +83,15 @@ func bdevRaidCreate(c *cli.Context) error {
 		return err
 	}
 
-	created, err := spdkCli.BdevRaidCreate(c.String("name"), spdktypes.BdevRaidLevel(c.String("level")), uint32(c.Uint64("strip-size-kb")), c.StringSlice("base-bdevs"))
+	created, err := spdkCli.BdevRaidCreateWithOptions(client.BdevRaidCreateOptions{
+		Name:        c.String("name"),
+		RaidLevel:   spdktypes.BdevRaidLevel(c.String("level")),
+		StripSizeKb: uint32(c.Uint64("strip-size-kb")),
+		BaseBdevs:   c.StringSlice("base-bdevs"),
+		UUID:        c.String("uuid"),
+		Superblock:  c.Bool("superblock"),
+		DeltaBitmap: c.Bool("delta-bitmap"),
+	})
 	if err != nil {
 		return err
 	}
@@ -193,3 +219,152 @@ func bdevRaidGrowBaseBdev(c *cli.Context) error {
 
 	return util.PrintObject(growed)
 }
+
+func BdevRaidAddBaseBdevCmd() cli.Command {
+	return cli.Command{
+		Name:  "add-base-bdev",
+		Usage: "add a bdev to an empty base bdev slot of an existing raid bdev, a rebuild starts in the background for a redundant raid: add-base-bdev --raid-name <RAID BDEV NAME> --base-name <BASE BDEV NAME>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "raid-name",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "base-name",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := bdevRaidAddBaseBdev(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run add base bdev to raid command")
+			}
+		},
+	}
+}
+
+func bdevRaidAddBaseBdev(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	added, err := spdkCli.BdevRaidAddBaseBdev(c.String("raid-name"), c.String("base-name"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(added)
+}
+
+func BdevRaidReplaceBaseBdevCmd() cli.Command {
+	return cli.Command{
+		Name:  "replace-base-bdev",
+		Usage: "replace a base bdev of an existing raid bdev and wait for the rebuild to complete: replace-base-bdev --raid-name <RAID BDEV NAME> [--old-base-name <OLD BASE BDEV NAME>] --new-base-name <NEW BASE BDEV NAME>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "raid-name",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "old-base-name",
+				Usage: "The base bdev to be removed. Skip it if the raid bdev already has an empty slot",
+			},
+			cli.StringFlag{
+				Name:     "new-base-name",
+				Required: true,
+			},
+			cli.DurationFlag{
+				Name:  "poll-interval",
+				Usage: "The interval of checking the rebuild progress",
+				Value: client.DefaultRaidRebuildPollInterval,
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := bdevRaidReplaceBaseBdev(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run replace base bdev of raid command")
+			}
+		},
+	}
+}
+
+func bdevRaidReplaceBaseBdev(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	progressCh := make(chan client.RaidRebuildProgress)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		for progress := range progressCh {
+			util.PrintProgressBar(progress.Percent, fmt.Sprintf("%d blocks rebuilt to %s", progress.Blocks, progress.TargetBaseBdev))
+		}
+	}()
+
+	err = spdkCli.ReplaceBaseBdev(context.Background(), c.String("raid-name"), c.String("old-base-name"), c.String("new-base-name"),
+		c.Duration("poll-interval"), progressCh)
+	close(progressCh)
+	<-doneCh
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	bdevRaidGetResp, err := spdkCli.BdevRaidGet(c.String("raid-name"), 0)
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(bdevRaidGetResp)
+}
+
+func BdevRaidWatchCmd() cli.Command {
+	return cli.Command{
+		Name: "watch",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "raid-name",
+				Usage: "If you want to watch one specific raid bdev, please input this",
+			},
+			cli.DurationFlag{
+				Name:  "interval",
+				Usage: "The interval of polling the raid bdevs",
+				Value: monitor.DefaultRaidMonitorInterval,
+			},
+		},
+		Usage: "watch the health of raid bdevs and print an event when one becomes degraded or offline, or recovers: \"watch\", or \"watch --raid-name <RAID BDEV NAME>\"",
+		Action: func(c *cli.Context) {
+			if err := bdevRaidWatch(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run watch bdev raid command")
+			}
+		},
+	}
+}
+
+func bdevRaidWatch(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	raidMonitor, err := monitor.NewRaidMonitor(spdkCli, monitor.RaidMonitorOptions{
+		RaidName: c.String("raid-name"),
+		Interval: c.Duration("interval"),
+	})
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- raidMonitor.Run(context.Background())
+	}()
+	for event := range raidMonitor.Events() {
+		if err := util.PrintObject(event); err != nil {
+			return err
+		}
+	}
+
+	return <-errCh
+}
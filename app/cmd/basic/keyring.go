@@ -0,0 +1,112 @@
+package basic
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/util"
+)
+
+func KeyringCmd() cli.Command {
+	return cli.Command{
+		Name: "keyring",
+		Subcommands: []cli.Command{
+			KeyringFileAddKeyCmd(),
+			KeyringFileRemoveKeyCmd(),
+			KeyringGetKeysCmd(),
+		},
+	}
+}
+
+func KeyringFileAddKeyCmd() cli.Command {
+	return cli.Command{
+		Name: "file-add-key",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "name",
+				Usage:    "Name of the key, which is referred by other commands",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "path",
+				Usage:    "Absolute path of the key file, which must be accessible by the owner only",
+				Required: true,
+			},
+		},
+		Usage: "add a key stored in a file to the keyring: file-add-key --name <KEY NAME> --path <KEY FILE PATH>",
+		Action: func(c *cli.Context) {
+			if err := keyringFileAddKey(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run add keyring file key command")
+			}
+		},
+	}
+}
+
+func keyringFileAddKey(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	added, err := spdkCli.KeyringFileAddKey(c.String("name"), c.String("path"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(added)
+}
+
+func KeyringFileRemoveKeyCmd() cli.Command {
+	return cli.Command{
+		Name:  "file-remove-key",
+		Usage: "remove a key added from a file from the keyring: file-remove-key <KEY NAME>",
+		Action: func(c *cli.Context) {
+			if err := keyringFileRemoveKey(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run remove keyring file key command")
+			}
+		},
+	}
+}
+
+func keyringFileRemoveKey(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	removed, err := spdkCli.KeyringFileRemoveKey(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(removed)
+}
+
+func KeyringGetKeysCmd() cli.Command {
+	return cli.Command{
+		Name:  "get-keys",
+		Usage: "list all keys in the keyring: get-keys",
+		Action: func(c *cli.Context) {
+			if err := keyringGetKeys(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run get keyring keys command")
+			}
+		},
+	}
+}
+
+func keyringGetKeys(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	keyList, err := spdkCli.KeyringGetKeys()
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(keyList)
+}
@@ -22,12 +22,22 @@ func NvmfCmd() cli.Command {
 			NvmfCreateSubsystemCmd(),
 			NvmfDeleteSubsystemCmd(),
 			NvmfGetSubsystemsCmd(),
+			NvmfGetSubsystemConnectionsCmd(),
+			NvmfSubsystemPauseCmd(),
+			NvmfSubsystemResumeCmd(),
 			NvmfSubsystemAddNsCmd(),
 			NvmfSubsystemRemoveNsCmd(),
 			NvmfSubsystemGetNssCmd(),
 			NvmfSubsystemAddListenerCmd(),
 			NvmfSubsystemRemoveListenerCmd(),
 			NvmfSubsystemGetListenersCmd(),
+			NvmfSubsystemListenerSetAnaStateCmd(),
+			NvmfSubsystemAddHostCmd(),
+			NvmfSubsystemRemoveHostCmd(),
+			NvmfSubsystemAllowAnyHostCmd(),
+			NvmfDiscoveryAddReferralCmd(),
+			NvmfDiscoveryRemoveReferralCmd(),
+			NvmfDiscoveryGetReferralsCmd(),
 		},
 	}
 }
@@ -42,6 +52,62 @@ func NvmfCreateTransportCmd() cli.Command {
 				Usage: "NVMe-oF target trtype: \"tcp\", \"rdma\" or \"pcie\"",
 				Value: string(spdktypes.NvmeTransportTypeTCP),
 			},
+			cli.StringFlag{
+				Name:  "tgt-name",
+				Usage: "Parent NVMe-oF target name",
+			},
+			cli.UintFlag{
+				Name:  "max-queue-depth",
+				Usage: "Max number of outstanding I/O per queue. 0 means the SPDK default",
+			},
+			cli.UintFlag{
+				Name:  "max-io-qpairs-per-ctrlr",
+				Usage: "Max number of I/O qpairs per controller. 0 means the SPDK default",
+			},
+			cli.UintFlag{
+				Name:  "in-capsule-data-size",
+				Usage: "Max number of in-capsule data size in bytes. 0 means the SPDK default",
+			},
+			cli.UintFlag{
+				Name:  "max-io-size",
+				Usage: "Max I/O size in bytes. 0 means the SPDK default",
+			},
+			cli.UintFlag{
+				Name:  "io-unit-size",
+				Usage: "I/O unit size in bytes. max-io-size can be at most 16 times of it. 0 means the SPDK default",
+			},
+			cli.UintFlag{
+				Name:  "max-aq-depth",
+				Usage: "Max number of admin cmds per AQ. 0 means the SPDK default",
+			},
+			cli.UintFlag{
+				Name:  "num-shared-buffers",
+				Usage: "The number of pooled data buffers available to the transport. 0 means the SPDK default",
+			},
+			cli.UintFlag{
+				Name:  "buf-cache-size",
+				Usage: "The number of shared buffers to reserve for each poll group. 0 means the SPDK default",
+			},
+			cli.UintFlag{
+				Name:  "abort-timeout-sec",
+				Usage: "Abort execution timeout value in seconds. 0 means the SPDK default",
+			},
+			cli.BoolFlag{
+				Name:  "dif-insert-or-strip",
+				Usage: "Enable DIF insert for write I/O and DIF strip for read I/O",
+			},
+			cli.BoolFlag{
+				Name:  "zcopy",
+				Usage: "Use zero-copy operations if the underlying bdev supports them",
+			},
+			cli.BoolTFlag{
+				Name:  "c2h-success",
+				Usage: "Enable the C2H success optimization. For the tcp transport only",
+			},
+			cli.UintFlag{
+				Name:  "sock-priority",
+				Usage: "The socket priority of the connection owned by this transport, from 0 to 6. For the tcp transport only",
+			},
 		},
 		Action: func(c *cli.Context) {
 			if err := nvmfCreateTransport(c); err != nil {
@@ -57,7 +123,28 @@ func nvmfCreateTransport(c *cli.Context) error {
 		return err
 	}
 
-	created, err := spdkCli.NvmfCreateTransport(spdktypes.NvmeTransportType(c.String("trtype")))
+	opts := client.NvmfCreateTransportOptions{
+		Trtype:              spdktypes.NvmeTransportType(c.String("trtype")),
+		TgtName:             c.String("tgt-name"),
+		MaxQueueDepth:       uint32(c.Uint("max-queue-depth")),
+		MaxIoQpairsPerCtrlr: uint32(c.Uint("max-io-qpairs-per-ctrlr")),
+		InCapsuleDataSize:   uint32(c.Uint("in-capsule-data-size")),
+		MaxIoSize:           uint32(c.Uint("max-io-size")),
+		IoUnitSize:          uint32(c.Uint("io-unit-size")),
+		MaxAqDepth:          uint32(c.Uint("max-aq-depth")),
+		NumSharedBuffers:    uint32(c.Uint("num-shared-buffers")),
+		BufCacheSize:        uint32(c.Uint("buf-cache-size")),
+		AbortTimeoutSec:     uint32(c.Uint("abort-timeout-sec")),
+		DifInsertOrStrip:    c.Bool("dif-insert-or-strip"),
+		Zcopy:               c.Bool("zcopy"),
+		SockPriority:        uint32(c.Uint("sock-priority")),
+	}
+	if c.IsSet("c2h-success") {
+		c2hSuccess := c.BoolT("c2h-success")
+		opts.C2HSuccess = &c2hSuccess
+	}
+
+	created, err := spdkCli.NvmfCreateTransportWithOptions(opts)
 	if err != nil {
 		return err
 	}
@@ -105,6 +192,40 @@ func NvmfCreateSubsystemCmd() cli.Command {
 	return cli.Command{
 		Name:  "subsystem-create",
 		Usage: "create a subsystem for nvmf: subsystem-create <SUBSYSTEM NQN>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "serial-number",
+				Usage: "The serial number of the subsystem, up to 20 characters. The host uses it for the /dev/disk/by-id names",
+			},
+			cli.StringFlag{
+				Name:  "model-number",
+				Usage: "The model number of the subsystem, up to 40 characters",
+			},
+			cli.BoolTFlag{
+				Name:  "allow-any-host",
+				Usage: "Allow any host to connect. Set it to false then add the allowed hosts by host-add",
+			},
+			cli.BoolFlag{
+				Name:  "ana-reporting",
+				Usage: "Enable the ANA reporting",
+			},
+			cli.UintFlag{
+				Name:  "max-namespaces",
+				Usage: "The max number of namespaces. 0 means unlimited",
+			},
+			cli.UintFlag{
+				Name:  "min-cntlid",
+				Usage: "The min controller ID",
+			},
+			cli.UintFlag{
+				Name:  "max-cntlid",
+				Usage: "The max controller ID",
+			},
+			cli.StringFlag{
+				Name:  "tgt-name",
+				Usage: "The parent NVMe-oF target name",
+			},
+		},
 		Action: func(c *cli.Context) {
 			if err := nvmfCreateSubsystem(c); err != nil {
 				logrus.WithError(err).Fatalf("Failed to run create nvmf subsystem command")
@@ -119,7 +240,17 @@ func nvmfCreateSubsystem(c *cli.Context) error {
 		return err
 	}
 
-	created, err := spdkCli.NvmfCreateSubsystem(c.Args().First())
+	created, err := spdkCli.NvmfCreateSubsystemWithOptions(client.NvmfCreateSubsystemOptions{
+		Nqn:           c.Args().First(),
+		AllowAnyHost:  c.BoolT("allow-any-host"),
+		AnaReporting:  c.Bool("ana-reporting"),
+		SerialNumber:  c.String("serial-number"),
+		ModelNumber:   c.String("model-number"),
+		MaxNamespaces: uint32(c.Uint("max-namespaces")),
+		MinCntlid:     uint16(c.Uint("min-cntlid")),
+		MaxCntlid:     uint16(c.Uint("max-cntlid")),
+		TgtName:       c.String("tgt-name"),
+	})
 	if err != nil {
 		return err
 	}
@@ -189,6 +320,109 @@ func nvmfGetSubsystems(c *cli.Context) error {
 	return util.PrintObject(subsystemList)
 }
 
+func NvmfGetSubsystemConnectionsCmd() cli.Command {
+	return cli.Command{
+		Name:  "subsystem-connections",
+		Usage: "list the hosts connected to all subsystems if nqn is not specified: subsystem-connections",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "nqn",
+				Usage: "NVMe-oF target subsystem NQN",
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := nvmfGetSubsystemConnections(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run get nvmf subsystem connections command")
+			}
+		},
+	}
+}
+
+func nvmfGetSubsystemConnections(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	subsystemList, err := spdkCli.NvmfGetSubsystems(c.String("nqn"), "")
+	if err != nil {
+		return err
+	}
+
+	connections := map[string][]client.SubsystemConnection{}
+	for _, subsystem := range subsystemList {
+		// The discovery subsystem has no controller of the hosts connecting to the namespaces
+		if subsystem.Subtype == spdktypes.NvmfSubsystemSubtypeDiscovery {
+			continue
+		}
+		subsystemConnections, err := spdkCli.GetSubsystemConnections(subsystem.Nqn)
+		if err != nil {
+			return err
+		}
+		connections[subsystem.Nqn] = subsystemConnections
+	}
+
+	return util.PrintObject(connections)
+}
+
+func NvmfSubsystemPauseCmd() cli.Command {
+	return cli.Command{
+		Name:  "subsystem-pause",
+		Usage: "pause the I/O of a subsystem for nvmf: subsystem-pause <SUBSYSTEM NQN>",
+		Flags: []cli.Flag{
+			cli.UintFlag{
+				Name:  "nsid",
+				Usage: "Pause only the namespace with the ID. 0 means all namespaces",
+			},
+		},
+		Action: func(c *cli.Context) {
+			if err := nvmfSubsystemPause(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run pause nvmf subsystem command")
+			}
+		},
+	}
+}
+
+func nvmfSubsystemPause(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	paused, err := spdkCli.NvmfSubsystemPause(c.Args().First(), uint32(c.Uint("nsid")))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(paused)
+}
+
+func NvmfSubsystemResumeCmd() cli.Command {
+	return cli.Command{
+		Name:  "subsystem-resume",
+		Usage: "resume the I/O of a paused subsystem for nvmf: subsystem-resume <SUBSYSTEM NQN>",
+		Action: func(c *cli.Context) {
+			if err := nvmfSubsystemResume(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run resume nvmf subsystem command")
+			}
+		},
+	}
+}
+
+func nvmfSubsystemResume(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	resumed, err := spdkCli.NvmfSubsystemResume(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(resumed)
+}
+
 func NvmfSubsystemAddNsCmd() cli.Command {
 	return cli.Command{
 		Name: "ns-add",
@@ -208,6 +442,30 @@ func NvmfSubsystemAddNsCmd() cli.Command {
 				Usage:    "Namespace globally unique identifier",
 				Required: false,
 			},
+			cli.UintFlag{
+				Name:  "nsid",
+				Usage: "Namespace ID. The first free ID is assigned if not specified",
+			},
+			cli.StringFlag{
+				Name:  "eui64",
+				Usage: "Namespace EUI-64 identifier, 16 hexadecimal digits",
+			},
+			cli.StringFlag{
+				Name:  "uuid",
+				Usage: "Namespace UUID. The UUID of the bdev is used if not specified",
+			},
+			cli.UintFlag{
+				Name:  "anagrpid",
+				Usage: "ANA group ID. The namespace ID is used if not specified",
+			},
+			cli.StringFlag{
+				Name:  "ptpl-file",
+				Usage: "The file persisting the reservations through power loss",
+			},
+			cli.StringFlag{
+				Name:  "tgt-name",
+				Usage: "Parent NVMe-oF target name",
+			},
 		},
 		Usage: "add a bdev as a namespace for subsystem of nvmf: ns-add <SUBSYSTEM NQN>",
 		Action: func(c *cli.Context) {
@@ -224,7 +482,17 @@ func nvmfSubsystemAddNs(c *cli.Context) error {
 		return err
 	}
 
-	added, err := spdkCli.NvmfSubsystemAddNs(c.String("nqn"), c.String("bdev-name"), c.String("nguid"))
+	added, err := spdkCli.NvmfSubsystemAddNsWithOptions(client.NvmfSubsystemAddNsOptions{
+		Nqn:      c.String("nqn"),
+		BdevName: c.String("bdev-name"),
+		Nsid:     uint32(c.Uint("nsid")),
+		Nguid:    c.String("nguid"),
+		Eui64:    c.String("eui64"),
+		UUID:     c.String("uuid"),
+		Anagrpid: uint32(c.Uint("anagrpid")),
+		PtplFile: c.String("ptpl-file"),
+		TgtName:  c.String("tgt-name"),
+	})
 	if err != nil {
 		return err
 	}
@@ -342,11 +610,14 @@ func NvmfSubsystemAddListenerCmd() cli.Command {
 			},
 			cli.StringFlag{
 				Name:  "adrfam",
-				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\"",
-				Value: string(spdktypes.NvmeAddressFamilyIPv4),
+				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\". Inferred from traddr if not specified",
+			},
+			cli.BoolFlag{
+				Name:  "secure-channel",
+				Usage: "Accept TLS connections only. The hosts need TLS pre-shared keys",
 			},
 		},
-		Usage: "add a listener for subsystem of nvmf: listener-add --nqn <SUBSYSTEM NQN> --traddr <IP> --trsvcid <PORT NUMBER>",
+		Usage: "add a listener for subsystem of nvmf: listener-add --nqn <SUBSYSTEM NQN> --traddr <IP> --trsvcid <PORT NUMBER> [--secure-channel]",
 		Action: func(c *cli.Context) {
 			if err := nvmfSubsystemAddListener(c); err != nil {
 				logrus.WithError(err).Fatalf("Failed to run add nvmf subsystem listener command")
@@ -361,8 +632,14 @@ func nvmfSubsystemAddListener(c *cli.Context) error {
 		return err
 	}
 
-	added, err := spdkCli.NvmfSubsystemAddListener(c.String("nqn"), c.String("traddr"), c.String("trsvcid"),
-		spdktypes.NvmeTransportType(c.String("trtype")), spdktypes.NvmeAddressFamily(c.String("adrfam")))
+	added, err := spdkCli.NvmfSubsystemAddListenerWithOptions(client.NvmfSubsystemAddListenerOptions{
+		Nqn:           c.String("nqn"),
+		Traddr:        c.String("traddr"),
+		Trsvcid:       c.String("trsvcid"),
+		Trtype:        spdktypes.NvmeTransportType(c.String("trtype")),
+		Adrfam:        spdktypes.NvmeAddressFamily(c.String("adrfam")),
+		SecureChannel: c.Bool("secure-channel"),
+	})
 	if err != nil {
 		return err
 	}
@@ -396,8 +673,7 @@ func NvmfSubsystemRemoveListenerCmd() cli.Command {
 			},
 			cli.StringFlag{
 				Name:  "adrfam",
-				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\"",
-				Value: string(spdktypes.NvmeAddressFamilyIPv4),
+				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\". Inferred from traddr if not specified",
 			},
 		},
 		Usage: "remove a listener from a subsystem of nvmf: listener-remove --nqn <SUBSYSTEM NQN> --traddr <IP> --trsvcid <PORT NUMBER>",
@@ -449,3 +725,331 @@ func nvmfSubsystemGetListeners(c *cli.Context) error {
 
 	return util.PrintObject(listenerList)
 }
+
+func NvmfSubsystemListenerSetAnaStateCmd() cli.Command {
+	return cli.Command{
+		Name: "listener-set-ana-state",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "nqn",
+				Usage:    "NVMe-oF target subnqn. It can be the nvmf subsystem nqn",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "traddr",
+				Usage:    "NVMe-oF target address: a ip or BDF",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "trsvcid",
+				Usage:    "NVMe-oF target trsvcid: a port number",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "trtype",
+				Usage: "NVMe-oF target trtype: \"tcp\", \"rdma\" or \"pcie\"",
+				Value: string(spdktypes.NvmeTransportTypeTCP),
+			},
+			cli.StringFlag{
+				Name:  "adrfam",
+				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\". Inferred from traddr if not specified",
+			},
+			cli.StringFlag{
+				Name:     "ana-state",
+				Usage:    "ANA state of the listener: \"optimized\", \"non_optimized\" or \"inaccessible\"",
+				Required: true,
+			},
+			cli.UintFlag{
+				Name:  "anagrpid",
+				Usage: "ANA group ID. All ANA groups are set if it is not specified",
+			},
+		},
+		Usage: "set the ANA state of a listener of a subsystem of nvmf: listener-set-ana-state --nqn <SUBSYSTEM NQN> --traddr <IP> --trsvcid <PORT NUMBER> --ana-state <ANA STATE>",
+		Action: func(c *cli.Context) {
+			if err := nvmfSubsystemListenerSetAnaState(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run set nvmf subsystem listener ANA state command")
+			}
+		},
+	}
+}
+
+func nvmfSubsystemListenerSetAnaState(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	set, err := spdkCli.NvmfSubsystemListenerSetAnaState(c.String("nqn"), c.String("traddr"), c.String("trsvcid"),
+		spdktypes.NvmeTransportType(c.String("trtype")), spdktypes.NvmeAddressFamily(c.String("adrfam")),
+		spdktypes.NvmfSubsystemListenerAnaState(c.String("ana-state")), uint32(c.Uint("anagrpid")))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(set)
+}
+
+func NvmfSubsystemAddHostCmd() cli.Command {
+	return cli.Command{
+		Name: "host-add",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "nqn",
+				Usage:    "Subsystem NQN",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "host",
+				Usage:    "Host NQN to allow",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "psk",
+				Usage: "Name of the keyring key used as the TLS pre-shared key of the host. Required for secure channel listeners",
+			},
+			cli.StringFlag{
+				Name:  "dhchap-key",
+				Usage: "Name of the keyring key used by the host to authenticate itself via DH-HMAC-CHAP. Optional",
+			},
+			cli.StringFlag{
+				Name:  "dhchap-ctrlr-key",
+				Usage: "Name of the keyring key used by the controller to authenticate itself to the host. Optional",
+			},
+		},
+		Usage: "allow a host to connect to a subsystem of nvmf, which takes effect once allow-any-host is disabled: host-add --nqn <SUBSYSTEM NQN> --host <HOST NQN>",
+		Action: func(c *cli.Context) {
+			if err := nvmfSubsystemAddHost(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run add nvmf subsystem host command")
+			}
+		},
+	}
+}
+
+func nvmfSubsystemAddHost(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	added, err := spdkCli.NvmfSubsystemAddHostWithOptions(client.NvmfSubsystemAddHostOptions{
+		Nqn:            c.String("nqn"),
+		HostNQN:        c.String("host"),
+		Psk:            c.String("psk"),
+		DhchapKey:      c.String("dhchap-key"),
+		DhchapCtrlrKey: c.String("dhchap-ctrlr-key"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(added)
+}
+
+func NvmfSubsystemRemoveHostCmd() cli.Command {
+	return cli.Command{
+		Name: "host-remove",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "nqn",
+				Usage:    "Subsystem NQN",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "host",
+				Usage:    "Host NQN to remove",
+				Required: true,
+			},
+		},
+		Usage: "disallow a host to connect to a subsystem of nvmf: host-remove --nqn <SUBSYSTEM NQN> --host <HOST NQN>",
+		Action: func(c *cli.Context) {
+			if err := nvmfSubsystemRemoveHost(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run remove nvmf subsystem host command")
+			}
+		},
+	}
+}
+
+func nvmfSubsystemRemoveHost(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	removed, err := spdkCli.NvmfSubsystemRemoveHost(c.String("nqn"), c.String("host"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(removed)
+}
+
+func NvmfSubsystemAllowAnyHostCmd() cli.Command {
+	return cli.Command{
+		Name: "allow-any-host",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "nqn",
+				Usage:    "Subsystem NQN",
+				Required: true,
+			},
+			cli.BoolTFlag{
+				Name:  "allow",
+				Usage: "Allow any host to connect. Set it to false to allow the added hosts only",
+			},
+		},
+		Usage: "enable or disable the host access control of a subsystem of nvmf: allow-any-host --nqn <SUBSYSTEM NQN> --allow=false",
+		Action: func(c *cli.Context) {
+			if err := nvmfSubsystemAllowAnyHost(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run allow any host for nvmf subsystem command")
+			}
+		},
+	}
+}
+
+func nvmfSubsystemAllowAnyHost(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	set, err := spdkCli.NvmfSubsystemAllowAnyHost(c.String("nqn"), c.BoolT("allow"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(set)
+}
+
+func NvmfDiscoveryAddReferralCmd() cli.Command {
+	return cli.Command{
+		Name: "referral-add",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "traddr",
+				Usage:    "NVMe-oF target address of the referral: a ip or BDF",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "trsvcid",
+				Usage:    "NVMe-oF target trsvcid of the referral: a port number",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "trtype",
+				Usage: "NVMe-oF target trtype: \"tcp\", \"rdma\" or \"pcie\"",
+				Value: string(spdktypes.NvmeTransportTypeTCP),
+			},
+			cli.StringFlag{
+				Name:  "adrfam",
+				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\". Inferred from traddr if not specified",
+			},
+			cli.StringFlag{
+				Name:  "subnqn",
+				Usage: "NQN of the subsystem referred to. The referral is to a discovery service if not specified",
+			},
+			cli.BoolFlag{
+				Name:  "secure-channel",
+				Usage: "The referral requires TLS connections",
+			},
+		},
+		Usage: "add a referral to the discovery service of nvmf: referral-add --traddr <IP> --trsvcid <PORT NUMBER>",
+		Action: func(c *cli.Context) {
+			if err := nvmfDiscoveryAddReferral(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run add nvmf discovery referral command")
+			}
+		},
+	}
+}
+
+func nvmfDiscoveryAddReferral(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	added, err := spdkCli.NvmfDiscoveryAddReferral(c.String("traddr"), c.String("trsvcid"),
+		spdktypes.NvmeTransportType(c.String("trtype")), spdktypes.NvmeAddressFamily(c.String("adrfam")), c.String("subnqn"), c.Bool("secure-channel"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(added)
+}
+
+func NvmfDiscoveryRemoveReferralCmd() cli.Command {
+	return cli.Command{
+		Name: "referral-remove",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:     "traddr",
+				Usage:    "NVMe-oF target address of the referral: a ip or BDF",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:     "trsvcid",
+				Usage:    "NVMe-oF target trsvcid of the referral: a port number",
+				Required: true,
+			},
+			cli.StringFlag{
+				Name:  "trtype",
+				Usage: "NVMe-oF target trtype: \"tcp\", \"rdma\" or \"pcie\"",
+				Value: string(spdktypes.NvmeTransportTypeTCP),
+			},
+			cli.StringFlag{
+				Name:  "adrfam",
+				Usage: "NVMe-oF target adrfam: \"ipv4\", \"ipv6\", \"ib\", \"fc\", \"intra_host\". Inferred from traddr if not specified",
+			},
+			cli.StringFlag{
+				Name:  "subnqn",
+				Usage: "NQN of the subsystem referred to",
+			},
+		},
+		Usage: "remove a referral from the discovery service of nvmf: referral-remove --traddr <IP> --trsvcid <PORT NUMBER>",
+		Action: func(c *cli.Context) {
+			if err := nvmfDiscoveryRemoveReferral(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run remove nvmf discovery referral command")
+			}
+		},
+	}
+}
+
+func nvmfDiscoveryRemoveReferral(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	removed, err := spdkCli.NvmfDiscoveryRemoveReferral(c.String("traddr"), c.String("trsvcid"),
+		spdktypes.NvmeTransportType(c.String("trtype")), spdktypes.NvmeAddressFamily(c.String("adrfam")), c.String("subnqn"))
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(removed)
+}
+
+func NvmfDiscoveryGetReferralsCmd() cli.Command {
+	return cli.Command{
+		Name:  "referral-get",
+		Usage: "list the referrals of the discovery service of nvmf: referral-get",
+		Action: func(c *cli.Context) {
+			if err := nvmfDiscoveryGetReferrals(c); err != nil {
+				logrus.WithError(err).Fatalf("Failed to run get nvmf discovery referrals command")
+			}
+		},
+	}
+}
+
+func nvmfDiscoveryGetReferrals(c *cli.Context) error {
+	spdkCli, err := client.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+
+	referralList, err := spdkCli.NvmfDiscoveryGetReferrals()
+	if err != nil {
+		return err
+	}
+
+	return util.PrintObject(referralList)
+}
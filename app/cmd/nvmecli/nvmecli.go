@@ -38,7 +38,7 @@ func Cmd() cli.Command {
 func DiscoverCmd() cli.Command {
 	return cli.Command{
 		Name: "discover",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:     "traddr",
 				Usage:    "NVMe-oF target address: a ip or BDF",
@@ -50,7 +50,12 @@ func DiscoverCmd() cli.Command {
 				Usage:    "NVMe-oF target trsvcid: a port number",
 				Required: true,
 			},
-		},
+			cli.StringFlag{
+				Name: "nqn-prefix",
+				Usage: "List all subsystems with the NQN prefix in the discovery log page, e.g., of a discovery service, rather than the one on trsvcid only. " +
+					"The host NQN, DH-HMAC-CHAP and TLS options apply with it",
+			},
+		}, connectOptionsFlags()...),
 		Usage: "Discover a NVMe-oF target: discover --traddr <IP> --trsvcid <PORT NUMBER>",
 		Action: func(c *cli.Context) {
 			if err := discover(c); err != nil {
@@ -66,6 +71,17 @@ func discover(c *cli.Context) error {
 		return err
 	}
 
+	if c.IsSet("nqn-prefix") {
+		entries, err := initiator.DiscoverTargets(c.String("traddr"), c.String("trsvcid"), c.String("nqn-prefix"), getConnectOptions(c), executor)
+		if err != nil {
+			return err
+		}
+		return util.PrintObject(entries)
+	}
+
+	if getConnectOptions(c) != (initiator.ConnectOptions{}) {
+		return fmt.Errorf("the host NQN, DH-HMAC-CHAP and TLS options of discover require --nqn-prefix")
+	}
 	subnqn, err := initiator.DiscoverTarget(c.String("traddr"), c.String("trsvcid"), executor)
 	if err != nil {
 		return err
@@ -77,7 +93,7 @@ func discover(c *cli.Context) error {
 func ConnectCmd() cli.Command {
 	return cli.Command{
 		Name: "connect",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:     "traddr",
 				Usage:    "NVMe-oF target address: a ip or BDF",
@@ -94,7 +110,7 @@ func ConnectCmd() cli.Command {
 				Usage:    "NVMe-oF target subsystem nqn",
 				Required: true,
 			},
-		},
+		}, connectOptionsFlags()...),
 		Usage: "Connect a NVMe-oF target subsystem as a NVMe device/initiator: connect --traddr <IP> --trsvcid <PORT NUMBER> --nqn <SUBSYSTEM NQN> ",
 		Action: func(c *cli.Context) {
 			if err := connect(c); err != nil {
@@ -110,7 +126,7 @@ func connect(c *cli.Context) error {
 		return err
 	}
 
-	controllerName, err := initiator.ConnectTarget(c.String("traddr"), c.String("trsvcid"), c.String("nqn"), executor)
+	controllerName, err := initiator.ConnectTargetWithOptions(c.String("traddr"), c.String("trsvcid"), c.String("nqn"), getConnectOptions(c), executor)
 	if err != nil {
 		return err
 	}
@@ -118,6 +134,42 @@ func connect(c *cli.Context) error {
 	return util.PrintObject(map[string]string{"controllerName": controllerName})
 }
 
+// connectOptionsFlags returns the flags parsed by getConnectOptions.
+func connectOptionsFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "hostnqn",
+			Usage: "Override the host NQN of this node. Optional",
+		},
+		cli.StringFlag{
+			Name:  "dhchap-secret",
+			Usage: "DH-HMAC-CHAP secret of the host in the \"DHHC-1:...\" representation. Optional",
+		},
+		cli.StringFlag{
+			Name:  "dhchap-ctrl-secret",
+			Usage: "DH-HMAC-CHAP secret of the controller for bidirectional authentication. Optional",
+		},
+		cli.BoolFlag{
+			Name:  "tls",
+			Usage: "Enable TLS, which is required by the secure channel listeners of the target",
+		},
+		cli.StringFlag{
+			Name:  "tls-key",
+			Usage: "TLS pre-shared key in the \"NVMeTLSkey-1:...\" interchange format. It implies --tls. Optional",
+		},
+	}
+}
+
+func getConnectOptions(c *cli.Context) initiator.ConnectOptions {
+	return initiator.ConnectOptions{
+		HostNQN:          c.String("hostnqn"),
+		DhchapSecret:     c.String("dhchap-secret"),
+		DhchapCtrlSecret: c.String("dhchap-ctrl-secret"),
+		TLS:              c.Bool("tls"),
+		TLSKey:           c.String("tls-key"),
+	}
+}
+
 func DisconnectCmd() cli.Command {
 	return cli.Command{
 		Name:  "disconnect",
@@ -205,6 +257,26 @@ func StartCmd() cli.Command {
 				Usage:    "NVMe-oF target subsystem nqn",
 				Required: true,
 			},
+			cli.StringFlag{
+				Name:  "hostnqn",
+				Usage: "Override the host NQN of this node. Optional",
+			},
+			cli.StringFlag{
+				Name:  "dhchap-secret",
+				Usage: "DH-HMAC-CHAP secret of the host in the \"DHHC-1:...\" representation. Optional",
+			},
+			cli.StringFlag{
+				Name:  "dhchap-ctrl-secret",
+				Usage: "DH-HMAC-CHAP secret of the controller for bidirectional authentication. Optional",
+			},
+			cli.BoolFlag{
+				Name:  "tls",
+				Usage: "Enable TLS, which is required by the secure channel listeners of the target",
+			},
+			cli.StringFlag{
+				Name:  "tls-key",
+				Usage: "TLS pre-shared key in the \"NVMeTLSkey-1:...\" interchange format. It implies --tls. Optional",
+			},
 		},
 		Usage: "Start a NVMe-oF initiator and make a device based on the name: start --name <NAME> --traddr <IP> --trsvcid <PORT NUMBER> --nqn <SUBSYSTEM NQN>",
 		Action: func(c *cli.Context) {
@@ -217,7 +289,8 @@ func StartCmd() cli.Command {
 
 func start(c *cli.Context) error {
 	nvmeTCPInfo := &initiator.NVMeTCPInfo{
-		SubsystemNQN: c.String("nqn"),
+		SubsystemNQN:   c.String("nqn"),
+		ConnectOptions: getConnectOptions(c),
 	}
 	i, err := initiator.NewInitiator(c.String("name"), c.GlobalString("host-proc"), nvmeTCPInfo, nil)
 	if err != nil {
@@ -37,6 +37,7 @@ func main() {
 		basic.BdevNvmeCmd(),
 		basic.BdevRaidCmd(),
 		basic.NvmfCmd(),
+		basic.KeyringCmd(),
 		basic.LogCmd(),
 		basic.UblkCmd(),
 
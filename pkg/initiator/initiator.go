@@ -69,6 +69,9 @@ type NVMeTCPInfo struct {
 	TransportServiceID string
 	ControllerName     string
 	NamespaceName      string
+
+	// ConnectOptions are used when discovering and connecting the target
+	ConnectOptions ConnectOptions
 }
 
 type UblkInfo struct {
@@ -137,7 +140,7 @@ func (i *Initiator) DiscoverNVMeTCPTarget(ip, port string) (string, error) {
 		defer lock.Unlock()
 	}
 
-	return DiscoverTarget(ip, port, i.executor)
+	return DiscoverTargetWithOptions(ip, port, i.connectOptions(), i.executor)
 }
 
 // ConnectNVMeTCPTarget connects to a target
@@ -150,7 +153,14 @@ func (i *Initiator) ConnectNVMeTCPTarget(ip, port, nqn string) (string, error) {
 		defer lock.Unlock()
 	}
 
-	return ConnectTarget(ip, port, nqn, i.executor)
+	return ConnectTargetWithOptions(ip, port, nqn, i.connectOptions(), i.executor)
+}
+
+func (i *Initiator) connectOptions() ConnectOptions {
+	if i.NVMeTCPInfo == nil {
+		return ConnectOptions{}
+	}
+	return i.NVMeTCPInfo.ConnectOptions
 }
 
 // DisconnectNVMeTCPTarget disconnects a target
@@ -318,7 +328,7 @@ func (i *Initiator) StartNvmeTCPInitiator(transportAddress, transportServiceID s
 	// Check if the initiator/NVMe-oF device is already launched and matches the params
 	err = i.loadNVMeDeviceInfoWithoutLock(i.NVMeTCPInfo.TransportAddress, i.NVMeTCPInfo.TransportServiceID, i.NVMeTCPInfo.SubsystemNQN)
 	if err == nil {
-		if i.NVMeTCPInfo.TransportAddress == transportAddress && i.NVMeTCPInfo.TransportServiceID == transportServiceID {
+		if i.NVMeTCPInfo.TransportAddress == types.NormalizeIP(transportAddress) && i.NVMeTCPInfo.TransportServiceID == transportServiceID {
 			err = i.LoadEndpointForNvmeTcpFrontend(false)
 			if err == nil {
 				i.logger.Info("NVMe-oF initiator is already launched with correct params")
@@ -522,14 +532,14 @@ func (i *Initiator) connectNVMeTCPTarget(transportAddress, transportServiceID st
 	}
 	for r := 0; r < maxRetries; r++ {
 		// Rerun this API for a discovered target should be fine
-		subsystemNQN, err := DiscoverTarget(transportAddress, transportServiceID, i.executor)
+		subsystemNQN, err := DiscoverTargetWithOptions(transportAddress, transportServiceID, i.connectOptions(), i.executor)
 		if err != nil {
 			i.logger.WithError(err).Warn("Failed to discover target")
 			time.Sleep(retryInterval)
 			continue
 		}
 
-		controllerName, err := ConnectTarget(transportAddress, transportServiceID, subsystemNQN, i.executor)
+		controllerName, err := ConnectTargetWithOptions(transportAddress, transportServiceID, subsystemNQN, i.connectOptions(), i.executor)
 		if err != nil {
 			i.logger.WithError(err).Warn("Failed to connect target")
 			time.Sleep(retryInterval)
@@ -3,6 +3,7 @@ package initiator
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -12,23 +13,77 @@ import (
 	"github.com/longhorn/go-spdk-helper/pkg/types"
 )
 
-// DiscoverTarget discovers a target
-func DiscoverTarget(ip, port string, executor *commonns.Executor) (subnqn string, err error) {
-	hostID, err := getHostID(executor)
+// ConnectOptions are the optional settings used to discover and connect a target.
+type ConnectOptions struct {
+	// HostNQN overrides the host NQN of this node. The target subsystem must allow it if the access control is enabled.
+	HostNQN string
+	// DhchapSecret is the DH-HMAC-CHAP secret of the host, in the "DHHC-1:..." representation. It enables in-band authentication.
+	DhchapSecret string
+	// DhchapCtrlSecret is the DH-HMAC-CHAP secret of the controller for bidirectional authentication. It requires DhchapSecret.
+	DhchapCtrlSecret string
+
+	// TLS enables TLS for the connections, which is required by the secure channel listeners of the target.
+	TLS bool
+	// TLSKey is the TLS pre-shared key in the "NVMeTLSkey-1:..." interchange format. It implies TLS.
+	// The key retained in the kernel keyring for the host NQN and the subsystem NQN is used if this is not specified.
+	TLSKey string
+}
+
+// String hides the secrets so that the options can be logged.
+func (o ConnectOptions) String() string {
+	return fmt.Sprintf("{HostNQN:%s DhchapSecret:%s DhchapCtrlSecret:%s TLS:%v TLSKey:%s}",
+		o.HostNQN, redact(o.DhchapSecret), redact(o.DhchapCtrlSecret), o.TLS, redact(o.TLSKey))
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "<redacted>"
+}
+
+func (o ConnectOptions) validate() error {
+	if o.DhchapCtrlSecret != "" && o.DhchapSecret == "" {
+		return fmt.Errorf("DH-HMAC-CHAP controller secret is set without host secret")
+	}
+	return nil
+}
+
+// getHostIdentity returns the host ID and the host NQN of this node, with the host NQN overridden by the options.
+func getHostIdentity(opts ConnectOptions, executor *commonns.Executor) (hostID, hostNQN string, err error) {
+	hostID, err = getHostID(executor)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	hostNQN = opts.HostNQN
+	if hostNQN == "" {
+		if hostNQN, err = showHostNQN(executor); err != nil {
+			return "", "", err
+		}
 	}
-	hostNQN, err := showHostNQN(executor)
+	return hostID, hostNQN, nil
+}
+
+// DiscoverTarget discovers a target
+func DiscoverTarget(ip, port string, executor *commonns.Executor) (subnqn string, err error) {
+	return DiscoverTargetWithOptions(ip, port, ConnectOptions{}, executor)
+}
+
+// DiscoverTargetWithOptions discovers a target as the host specified by the options.
+// The target lists only the subsystems the host is allowed to connect to. The secrets are not used, but TLS is, since
+// the discovery goes through the same listener as the connection.
+func DiscoverTargetWithOptions(ip, port string, opts ConnectOptions, executor *commonns.Executor) (subnqn string, err error) {
+	hostID, hostNQN, err := getHostIdentity(opts, executor)
 	if err != nil {
 		return "", err
 	}
 
-	entries, err := discovery(hostID, hostNQN, ip, port, executor)
+	entries, err := discovery(hostID, hostNQN, ip, port, opts, executor)
 	if err != nil {
 		return "", err
 	}
 
-	for _, entry := range entries {
+	for _, entry := range filterDiscoveryPageEntries(entries, "") {
 		if entry.TrsvcID == port {
 			return entry.Subnqn, nil
 		}
@@ -37,24 +92,62 @@ func DiscoverTarget(ip, port string, executor *commonns.Executor) (subnqn string
 	return "", fmt.Errorf("found empty subnqn after nvme discover for %s:%s", ip, port)
 }
 
+// DiscoverTargets lists the NVMe subsystems in the discovery log page of a target, e.g., of the discovery service
+// listening on a well-known port, which may list the subsystems on other ports or other targets.
+// Only the subsystems with NQN prefix are returned if nqnPrefix is specified, e.g., types.NQNPrefix.
+func DiscoverTargets(ip, port, nqnPrefix string, opts ConnectOptions, executor *commonns.Executor) ([]DiscoveryPageEntry, error) {
+	hostID, hostNQN, err := getHostIdentity(opts, executor)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := discovery(hostID, hostNQN, ip, port, opts, executor)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterDiscoveryPageEntries(entries, nqnPrefix), nil
+}
+
+// filterDiscoveryPageEntries returns the NVMe subsystem entries with the NQN prefix, leaving out the discovery subsystems.
+func filterDiscoveryPageEntries(entries []DiscoveryPageEntry, nqnPrefix string) []DiscoveryPageEntry {
+	filtered := []DiscoveryPageEntry{}
+	for _, entry := range entries {
+		// The subtype of a discovery subsystem varies with the nvme-cli versions, e.g., "discovery subsystem" in old ones
+		if strings.Contains(entry.SubType, "discovery") {
+			continue
+		}
+		if nqnPrefix != "" && !strings.HasPrefix(entry.Subnqn, nqnPrefix) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
 // ConnectTarget connects to a target
 func ConnectTarget(ip, port, nqn string, executor *commonns.Executor) (controllerName string, err error) {
+	return ConnectTargetWithOptions(ip, port, nqn, ConnectOptions{}, executor)
+}
+
+// ConnectTargetWithOptions connects to a target as the host specified by the options, authenticating with the secrets if any.
+func ConnectTargetWithOptions(ip, port, nqn string, opts ConnectOptions, executor *commonns.Executor) (controllerName string, err error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
 	// Trying to connect an existing subsystem will error out with exit code 114.
 	// Hence, it's better to check the existence first.
 	if devices, err := GetDevices(ip, port, nqn, executor); err == nil && len(devices) > 0 {
 		return devices[0].Controllers[0].Controller, nil
 	}
 
-	hostNQN, err := showHostNQN(executor)
-	if err != nil {
-		return "", err
-	}
-	hostID, err := getHostID(executor)
+	hostID, hostNQN, err := getHostIdentity(opts, executor)
 	if err != nil {
 		return "", err
 	}
 
-	return connect(hostID, hostNQN, nqn, DefaultTransportType, ip, port, executor)
+	return connect(hostID, hostNQN, nqn, DefaultTransportType, ip, port, opts, executor)
 }
 
 // DisconnectTarget disconnects from a target
@@ -131,7 +224,7 @@ func GetDevices(ip, port, nqn string, executor *commonns.Executor) (devices []De
 		}
 		for _, c := range d.Controllers {
 			controllerIP, controllerPort := GetIPAndPortFromControllerAddress(c.Address)
-			if ip != "" && ip != controllerIP {
+			if ip != "" && types.NormalizeIP(ip) != controllerIP {
 				continue
 			}
 			if port != "" && port != controllerPort {
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
+
 	commonns "github.com/longhorn/go-common-libs/ns"
 
 	"github.com/longhorn/go-spdk-helper/pkg/types"
@@ -29,8 +31,16 @@ type Device struct {
 	Namespaces   []Namespace
 }
 
+const (
+	DiscoverySubTypeNVMe             = "nvme subsystem"
+	DiscoverySubTypeReferral         = "discovery subsystem referral"
+	DiscoverySubTypeCurrentDiscovery = "current discovery subsystem"
+)
+
 type DiscoveryPageEntry struct {
 	PortID  uint16 `json:"portid"`
+	Trtype  string `json:"trtype"`
+	Adrfam  string `json:"adrfam"`
 	TrsvcID string `json:"trsvcid"`
 	Subnqn  string `json:"subnqn"`
 	Traddr  string `json:"traddr"`
@@ -232,11 +242,11 @@ func getHostID(executor *commonns.Executor) (string, error) {
 	return "", err
 }
 
-func discovery(hostID, hostNQN, ip, port string, executor *commonns.Executor) ([]DiscoveryPageEntry, error) {
+func discovery(hostID, hostNQN, ip, port string, connectOpts ConnectOptions, executor *commonns.Executor) ([]DiscoveryPageEntry, error) {
 	opts := []string{
 		"discover",
 		"-t", DefaultTransportType,
-		"-a", ip,
+		"-a", types.NormalizeIP(ip),
 		"-s", port,
 		"-o", "json",
 	}
@@ -246,6 +256,7 @@ func discovery(hostID, hostNQN, ip, port string, executor *commonns.Executor) ([
 	if hostNQN != "" {
 		opts = append(opts, "-q", hostNQN)
 	}
+	opts = append(opts, tlsOpts(connectOpts)...)
 
 	// A valid output is like below:
 	// # nvme discover -t tcp -a 10.42.2.20 -s 20011 -o json
@@ -281,7 +292,7 @@ func discovery(hostID, hostNQN, ip, port string, executor *commonns.Executor) ([
 	// nvme discover does not respect the -s option, so we need to filter the output
 	outputStr, err := executor.Execute(nil, nvmeBinary, opts, types.ExecuteTimeout)
 	if err != nil {
-		return nil, err
+		return nil, scrubSecrets(err, connectOpts)
 	}
 
 	jsonStr, err := extractJSONString(outputStr)
@@ -301,9 +312,32 @@ func discovery(hostID, hostNQN, ip, port string, executor *commonns.Executor) ([
 	return output.Entries, nil
 }
 
-func connect(hostID, hostNQN, nqn, transpotType, ip, port string, executor *commonns.Executor) (string, error) {
-	var err error
+func connect(hostID, hostNQN, nqn, transpotType, ip, port string, connectOpts ConnectOptions, executor *commonns.Executor) (string, error) {
+	opts := connectArgs(hostID, hostNQN, nqn, transpotType, ip, port, connectOpts)
+
+	// The output example:
+	// {
+	//  "device" : "nvme0"
+	// }
+	outputStr, err := executor.Execute(nil, nvmeBinary, opts, types.ExecuteTimeout)
+	if err != nil {
+		return "", scrubSecrets(err, connectOpts)
+	}
+
+	jsonStr, err := extractJSONString(outputStr)
+	if err != nil {
+		return "", err
+	}
+
+	output := map[string]string{}
+	if err := json.Unmarshal([]byte(jsonStr), &output); err != nil {
+		return "", err
+	}
+
+	return output["device"], nil
+}
 
+func connectArgs(hostID, hostNQN, nqn, transpotType, ip, port string, connectOpts ConnectOptions) []string {
 	opts := []string{
 		"connect",
 		"-t", transpotType,
@@ -321,32 +355,48 @@ func connect(hostID, hostNQN, nqn, transpotType, ip, port string, executor *comm
 		opts = append(opts, "-q", hostNQN)
 	}
 	if ip != "" {
-		opts = append(opts, "-a", ip)
+		opts = append(opts, "-a", types.NormalizeIP(ip))
 	}
 	if port != "" {
 		opts = append(opts, "-s", port)
 	}
-
-	// The output example:
-	// {
-	//  "device" : "nvme0"
-	// }
-	outputStr, err := executor.Execute(nil, nvmeBinary, opts, types.ExecuteTimeout)
-	if err != nil {
-		return "", err
+	if connectOpts.DhchapSecret != "" {
+		opts = append(opts, "--dhchap-secret", connectOpts.DhchapSecret)
+	}
+	if connectOpts.DhchapCtrlSecret != "" {
+		opts = append(opts, "--dhchap-ctrl-secret", connectOpts.DhchapCtrlSecret)
 	}
+	return append(opts, tlsOpts(connectOpts)...)
+}
 
-	jsonStr, err := extractJSONString(outputStr)
-	if err != nil {
-		return "", err
+func tlsOpts(connectOpts ConnectOptions) []string {
+	if !connectOpts.TLS && connectOpts.TLSKey == "" {
+		return nil
+	}
+	opts := []string{"--tls"}
+	if connectOpts.TLSKey != "" {
+		opts = append(opts, "--tls-key", connectOpts.TLSKey)
 	}
+	return opts
+}
 
-	output := map[string]string{}
-	if err := json.Unmarshal([]byte(jsonStr), &output); err != nil {
-		return "", err
+// scrubSecrets redacts the secrets of the options from the error of a nvme-cli command.
+// The error of the executor includes the full command line, hence it cannot be returned or logged as it is.
+func scrubSecrets(err error, connectOpts ConnectOptions) error {
+	if err == nil {
+		return nil
 	}
 
-	return output["device"], nil
+	msg := err.Error()
+	for _, secret := range []string{connectOpts.DhchapSecret, connectOpts.DhchapCtrlSecret, connectOpts.TLSKey} {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, redact(secret))
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
 }
 
 func disconnect(nqn string, executor *commonns.Executor) error {
@@ -389,7 +439,8 @@ func extractJSONString(str string) (string, error) {
 }
 
 // GetIPAndPortFromControllerAddress returns the IP and port from the controller address
-// Input can be either "traddr=10.42.2.18 trsvcid=20006" or "traddr=10.42.2.18,trsvcid=20006"
+// Input can be either "traddr=10.42.2.18 trsvcid=20006" or "traddr=10.42.2.18,trsvcid=20006",
+// and the IPv6 one is like "traddr=fd00::18,trsvcid=20006,src_addr=fd00::19". The IP is normalized, see types.NormalizeIP.
 func GetIPAndPortFromControllerAddress(address string) (string, string) {
 	var traddr, trsvcid string
 
@@ -398,16 +449,15 @@ func GetIPAndPortFromControllerAddress(address string) (string, string) {
 	})
 
 	for _, part := range parts {
-		keyVal := strings.Split(part, "=")
-		if len(keyVal) == 2 {
-			key := strings.TrimSpace(keyVal[0])
-			value := strings.TrimSpace(keyVal[1])
-			switch key {
-			case "traddr":
-				traddr = value
-			case "trsvcid":
-				trsvcid = value
-			}
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "traddr":
+			traddr = types.NormalizeIP(strings.TrimSpace(value))
+		case "trsvcid":
+			trsvcid = strings.TrimSpace(value)
 		}
 	}
 
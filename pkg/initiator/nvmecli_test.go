@@ -0,0 +1,111 @@
+package initiator
+
+import (
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	commonns "github.com/longhorn/go-common-libs/ns"
+	commontypes "github.com/longhorn/go-common-libs/types"
+
+	"github.com/longhorn/go-spdk-helper/pkg/types"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+func (s *TestSuite) TestGetIPAndPortFromControllerAddress(c *C) {
+	testCases := []struct {
+		address string
+		ip      string
+		port    string
+	}{
+		{"traddr=10.42.2.18 trsvcid=20006", "10.42.2.18", "20006"},
+		{"traddr=10.42.2.18,trsvcid=20006", "10.42.2.18", "20006"},
+		{"traddr=10.42.2.18,trsvcid=20006,src_addr=10.42.2.19", "10.42.2.18", "20006"},
+		{"traddr=::1,trsvcid=20006", types.LocalIPv6, "20006"},
+		{"traddr=::1,trsvcid=20006,src_addr=::1", types.LocalIPv6, "20006"},
+		{"traddr=[::1] trsvcid=20006", types.LocalIPv6, "20006"},
+		{"traddr=0:0:0:0:0:0:0:1,trsvcid=20006", types.LocalIPv6, "20006"},
+		{"traddr=fe80::1%eth0,trsvcid=20006", "fe80::1%eth0", "20006"},
+		{"", "", ""},
+	}
+
+	for idx, testCase := range testCases {
+		comment := Commentf("test case %d", idx)
+		ip, port := GetIPAndPortFromControllerAddress(testCase.address)
+		c.Assert(ip, Equals, testCase.ip, comment)
+		c.Assert(port, Equals, testCase.port, comment)
+	}
+}
+
+func (s *TestSuite) TestFilterDiscoveryPageEntries(c *C) {
+	entries := []DiscoveryPageEntry{
+		{Subnqn: "nqn.2014-08.org.nvmexpress.discovery", Traddr: types.LocalIPv6, TrsvcID: "8009", SubType: DiscoverySubTypeCurrentDiscovery},
+		{Subnqn: types.GetNQN("vol1"), Traddr: types.LocalIPv6, TrsvcID: "20001", SubType: DiscoverySubTypeNVMe},
+		{Subnqn: types.GetNQN("vol2"), Traddr: types.LocalIPv6, TrsvcID: "20002", SubType: DiscoverySubTypeNVMe},
+		{Subnqn: "nqn.2016-06.io.spdk:cnode1", Traddr: types.LocalIPv6, TrsvcID: "4420", SubType: DiscoverySubTypeNVMe},
+		{Traddr: "fd00::2", TrsvcID: "8009", SubType: DiscoverySubTypeReferral},
+	}
+
+	filtered := filterDiscoveryPageEntries(entries, types.NQNPrefix)
+	c.Assert(filtered, DeepEquals, entries[1:3])
+
+	filtered = filterDiscoveryPageEntries(entries, "")
+	c.Assert(filtered, DeepEquals, entries[1:4])
+}
+
+func (s *TestSuite) TestConnectErrorHidesSecrets(c *C) {
+	executor, err := commonns.NewNamespaceExecutor(commontypes.ProcessNone, "/proc", nil)
+	if err != nil {
+		c.Skip("nsenter is unavailable: " + err.Error())
+	}
+
+	opts := ConnectOptions{
+		DhchapSecret:     "DHHC-1:00:aG9zdC1zZWNyZXQtZm9yLWxvbmdob3JuLXRlc3Q=:",
+		DhchapCtrlSecret: "DHHC-1:00:Y3RybC1zZWNyZXQtZm9yLWxvbmdob3JuLXRlc3Q=:",
+		TLSKey:           "NVMeTLSkey-1:01:dGxzLWtleS1mb3ItbG9uZ2hvcm4tdGVzdC0wMTIzNDU2Nzg5:",
+	}
+	// There is no target listening on the port, hence the connect fails even if nvme-cli is installed
+	_, err = connect("", "", "nqn.2023-01.io.longhorn.spdk:vol", DefaultTransportType, "127.0.0.1", "1", opts, executor)
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.Error(), opts.DhchapSecret), Equals, false)
+	c.Assert(strings.Contains(err.Error(), opts.DhchapCtrlSecret), Equals, false)
+	c.Assert(strings.Contains(err.Error(), opts.TLSKey), Equals, false)
+	c.Assert(strings.Contains(err.Error(), "--dhchap-secret <redacted>"), Equals, true)
+}
+
+func (s *TestSuite) TestConnectArgsTLS(c *C) {
+	nqn := "nqn.2023-01.io.longhorn.spdk:vol"
+	baseArgs := []string{
+		"connect",
+		"-t", DefaultTransportType,
+		"--nqn", nqn,
+		"--ctrl-loss-tmo", "30",
+		"--keep-alive-tmo", "5",
+		"--reconnect-delay", "2",
+		"-o", "json",
+		"-q", "nqn.2014-08.org.nvmexpress:uuid:host",
+		"-a", "fd00::18",
+		"-s", "20006",
+	}
+
+	testCases := []struct {
+		opts ConnectOptions
+		args []string
+	}{
+		{ConnectOptions{}, nil},
+		{ConnectOptions{TLS: true}, []string{"--tls"}},
+		{ConnectOptions{TLSKey: "NVMeTLSkey-1:01:a2V5:"}, []string{"--tls", "--tls-key", "NVMeTLSkey-1:01:a2V5:"}},
+		{ConnectOptions{TLS: true, TLSKey: "NVMeTLSkey-1:01:a2V5:"}, []string{"--tls", "--tls-key", "NVMeTLSkey-1:01:a2V5:"}},
+	}
+	for idx, tc := range testCases {
+		args := connectArgs("", "nqn.2014-08.org.nvmexpress:uuid:host", nqn, DefaultTransportType, "[fd00::18]", "20006", tc.opts)
+		c.Assert(args, DeepEquals, append(append([]string{}, baseArgs...), tc.args...), Commentf("test case %d", idx))
+		c.Assert(tlsOpts(tc.opts), DeepEquals, tc.args, Commentf("test case %d", idx))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
 	"os"
@@ -50,6 +51,14 @@ type messageWrapper struct {
 	responseChan chan *Response
 }
 
+// newDecoder returns a decoder keeping the numbers in the results as json.Number,
+// so that the 64-bit and 128-bit counters reported by SPDK do not lose precision.
+func newDecoder(r io.Reader) *json.Decoder {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder
+}
+
 func NewClient(ctx context.Context, conn net.Conn) *Client {
 	c := &Client{
 		ctx: ctx,
@@ -64,7 +73,7 @@ func NewClient(ctx context.Context, conn net.Conn) *Client {
 		idCounter: rand.Uint32() % 10000,
 
 		encoder: json.NewEncoder(conn),
-		decoder: json.NewDecoder(conn),
+		decoder: newDecoder(conn),
 
 		sem:                 make(chan interface{}, DefaultConcurrentLimit),
 		msgWrapperQueue:     make(chan *messageWrapper, DefaultConcurrentLimit),
@@ -120,7 +129,7 @@ func (c *Client) SendMsgWithTimeout(method string, params interface{}, timeout t
 		return nil, err
 	}
 
-	connDecoder := json.NewDecoder(bufio.NewReader(c.conn))
+	connDecoder := newDecoder(bufio.NewReader(c.conn))
 	for count := 0; count <= int(timeout/time.Second); count++ {
 		if connDecoder.More() {
 			break
@@ -226,7 +235,7 @@ func (c *Client) read() {
 				logrus.WithError(err).Errorf("Failed to decoding response during read")
 
 				// In case of the cached error info of the old decoder fails the following response, it's better to recreate the decoder.
-				c.decoder = json.NewDecoder(c.conn)
+				c.decoder = newDecoder(c.conn)
 				continue
 			}
 
@@ -0,0 +1,121 @@
+// Package jsonrpctest provides a fake SPDK JSON-RPC server for the unit tests.
+package jsonrpctest
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+)
+
+// Request is a request received by Server.
+type Request struct {
+	Method string
+	Params json.RawMessage
+}
+
+// Handler returns the result or the error of a request.
+type Handler func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError)
+
+// Server stands in for spdk_tgt. It records the requests and responds with the handlers,
+// or with true for the methods without a handler.
+type Server struct {
+	sync.Mutex
+
+	handlers map[string]Handler
+	requests []Request
+}
+
+func NewServer(handlers map[string]Handler) *Server {
+	if handlers == nil {
+		handlers = map[string]Handler{}
+	}
+	return &Server{
+		handlers: handlers,
+	}
+}
+
+// Handle sets the handler of the method.
+func (s *Server) Handle(method string, handler Handler) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.handlers[method] = handler
+}
+
+// Dial returns the client side of a connection with the server, and the function to close the connection.
+func (s *Server) Dial() (net.Conn, func()) {
+	clientConn, serverConn := net.Pipe()
+
+	go s.serve(serverConn)
+
+	return clientConn, func() {
+		clientConn.Close()
+		serverConn.Close()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+
+	for {
+		var msg struct {
+			ID     uint32          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			return
+		}
+
+		s.Lock()
+		s.requests = append(s.requests, Request{Method: msg.Method, Params: msg.Params})
+		handler := s.handlers[msg.Method]
+		s.Unlock()
+
+		resp := jsonrpc.Response{
+			ID:      msg.ID,
+			Version: "2.0",
+			Result:  true,
+		}
+		if handler != nil {
+			resp.Result, resp.ErrorInfo = handler(msg.Params)
+		}
+		if err := encoder.Encode(&resp); err != nil {
+			return
+		}
+	}
+}
+
+// Methods returns the methods of the received requests in order.
+func (s *Server) Methods() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	methods := []string{}
+	for _, req := range s.requests {
+		methods = append(methods, req.Method)
+	}
+	return methods
+}
+
+// RequestsOf decodes the params of the received requests with the method into out,
+// which is a pointer to a slice of the params type.
+func (s *Server) RequestsOf(method string, out interface{}) error {
+	s.Lock()
+	defer s.Unlock()
+
+	params := []json.RawMessage{}
+	for _, req := range s.requests {
+		if req.Method == method {
+			params = append(params, req.Params)
+		}
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
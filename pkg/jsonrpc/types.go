@@ -27,6 +27,7 @@ type RespErrorMsg string
 type RespErrorCode int32
 
 const (
+	RespErrorCodeNoSuchFile    = -2
 	RespErrorCodeNoSuchProcess = -3
 	RespErrorCodeNoFileExists  = -17
 	RespErrorCodeNoSuchDevice  = -19
@@ -73,6 +74,19 @@ func IsJSONRPCRespErrorNoSuchProcess(err error) bool {
 	return responseError.Code == RespErrorCodeNoSuchProcess
 }
 
+func IsJSONRPCRespErrorNoSuchFile(err error) bool {
+	jsonRPCError, ok := err.(JSONClientError)
+	if !ok {
+		return false
+	}
+	responseError, ok := jsonRPCError.ErrorDetail.(*ResponseError)
+	if !ok {
+		return false
+	}
+
+	return responseError.Code == RespErrorCodeNoSuchFile
+}
+
 func IsJSONRPCRespErrorNoSuchDevice(err error) bool {
 	jsonRPCError, ok := err.(JSONClientError)
 	if !ok {
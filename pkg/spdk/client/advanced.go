@@ -1,21 +1,64 @@
 package client
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/types"
 
 	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
 )
 
 // AddDevice adds a device with the given device path, name, and cluster size.
 func (c *Client) AddDevice(devicePath, name string, clusterSize uint32) (bdevAioName, lvsName, lvsUUID string, err error) {
+	return c.AddDeviceWithOptions(AddDeviceOptions{
+		DevicePath:  devicePath,
+		Name:        name,
+		ClusterSize: clusterSize,
+	})
+}
+
+// AddDeviceOptions are the options of AddDeviceWithOptions.
+// See BdevLvolCreateLvstoreOptions for the meaning of the lvstore options.
+type AddDeviceOptions struct {
+	DevicePath string
+	// Name is optional. It is both the aio bdev name and the lvstore name, which is the file name of the device by default.
+	Name string
+
+	ClusterSize               uint32
+	ClearMethod               spdktypes.BdevLvolClearMethod
+	NumMdPagesPerClusterRatio uint32
+}
+
+// AddDeviceWithOptions adds a device with the options.
+// The lvstore options take effect only if the lvstore does not exist on the device yet.
+func (c *Client) AddDeviceWithOptions(opts AddDeviceOptions) (bdevAioName, lvsName, lvsUUID string, err error) {
 	// Use the file name as aio name and lvs name if name is not specified.
+	name := opts.Name
 	if name == "" {
-		name = filepath.Base(devicePath)
+		name = filepath.Base(opts.DevicePath)
+	}
+
+	lvsOpts := BdevLvolCreateLvstoreOptions{
+		BdevName:                  name,
+		LvsName:                   name,
+		ClusterSize:               opts.ClusterSize,
+		ClearMethod:               opts.ClearMethod,
+		NumMdPagesPerClusterRatio: opts.NumMdPagesPerClusterRatio,
+	}
+	// Validate the options before creating the aio bdev so that nothing is left behind
+	if err := lvsOpts.validate(); err != nil {
+		return "", "", "", errors.Wrapf(err, "invalid options for adding device %s", opts.DevicePath)
 	}
 
-	if _, err := c.BdevAioCreate(devicePath, name, 4096); err != nil {
+	if _, err := c.BdevAioCreate(opts.DevicePath, name, 4096); err != nil {
 		return "", "", "", err
 	}
 
@@ -32,7 +75,7 @@ func (c *Client) AddDevice(devicePath, name string, clusterSize uint32) (bdevAio
 		}
 	}
 	if !lvsCreated {
-		if lvsUUID, err = c.BdevLvolCreateLvstore(name, name, clusterSize); err != nil {
+		if lvsUUID, err = c.BdevLvolCreateLvstoreWithOptions(lvsOpts); err != nil {
 			return "", "", "", err
 		}
 	}
@@ -53,19 +96,439 @@ func (c *Client) DeleteDevice(bdevAioName, lvsName string) (err error) {
 	return nil
 }
 
+const (
+	DefaultDeviceGrowWaitTimeout = 10 * time.Second
+
+	deviceGrowWaitInterval = 500 * time.Millisecond
+)
+
+// DeviceGrowInfo reports the aio bdev and the lvstore before and after growing a device.
+type DeviceGrowInfo struct {
+	BdevAioName string `json:"bdev_aio_name"`
+	LvsName     string `json:"lvs_name"`
+
+	NumBlocksBefore uint64 `json:"num_blocks_before"`
+	NumBlocksAfter  uint64 `json:"num_blocks_after"`
+
+	TotalDataClustersBefore uint64 `json:"total_data_clusters_before"`
+	TotalDataClustersAfter  uint64 `json:"total_data_clusters_after"`
+	FreeClustersBefore      uint64 `json:"free_clusters_before"`
+	FreeClustersAfter       uint64 `json:"free_clusters_after"`
+}
+
+// GrowDevice makes SPDK notice the enlarged file or block device under the aio bdev, then grows the lvstore on it.
+// The lvstore is grown right after the rescan, hence it also catches up with an aio bdev enlarged by a previous rescan,
+// e.g., a retry after a failed call. If the lvstore cannot grow, the aio bdev is rescanned until its size changes
+// within the timeout, in case the enlarged size is not visible yet. Otherwise, the lvstore is left as it is.
+func (c *Client) GrowDevice(bdevAioName, lvsName string, timeout time.Duration) (*DeviceGrowInfo, error) {
+	if timeout <= 0 {
+		timeout = DefaultDeviceGrowWaitTimeout
+	}
+
+	bdevAio, err := c.getBdevAio(bdevAioName)
+	if err != nil {
+		return nil, err
+	}
+	lvs, err := c.getLvstore(lvsName)
+	if err != nil {
+		return nil, err
+	}
+	info := &DeviceGrowInfo{
+		BdevAioName: bdevAioName,
+		LvsName:     lvsName,
+
+		NumBlocksBefore:         bdevAio.NumBlocks,
+		NumBlocksAfter:          bdevAio.NumBlocks,
+		TotalDataClustersBefore: lvs.TotalDataClusters,
+		TotalDataClustersAfter:  lvs.TotalDataClusters,
+		FreeClustersBefore:      lvs.FreeClusters,
+		FreeClustersAfter:       lvs.FreeClusters,
+	}
+
+	for start, first := time.Now(), true; ; first = false {
+		if _, err := c.BdevAioRescan(bdevAioName); err != nil {
+			return nil, err
+		}
+		if bdevAio, err = c.getBdevAio(bdevAioName); err != nil {
+			return nil, err
+		}
+		sizeChanged := bdevAio.NumBlocks != info.NumBlocksAfter
+		info.NumBlocksAfter = bdevAio.NumBlocks
+
+		// Growing the lvstore is a no-op if it already covers the whole aio bdev
+		if first || sizeChanged {
+			if _, err := c.BdevLvolGrowLvstore(lvsName, ""); err != nil {
+				return nil, err
+			}
+			if lvs, err = c.getLvstore(lvsName); err != nil {
+				return nil, err
+			}
+			info.TotalDataClustersAfter = lvs.TotalDataClusters
+			info.FreeClustersAfter = lvs.FreeClusters
+			if info.TotalDataClustersAfter != info.TotalDataClustersBefore {
+				return info, nil
+			}
+		}
+
+		if time.Since(start) > timeout {
+			logrus.Infof("Skipping growing lvstore %s since the size of aio bdev %s does not change after rescan", lvsName, bdevAioName)
+			return info, nil
+		}
+		time.Sleep(deviceGrowWaitInterval)
+	}
+}
+
+func (c *Client) getBdevAio(name string) (*spdktypes.BdevInfo, error) {
+	bdevAioList, err := c.BdevAioGet(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(bdevAioList) != 1 {
+		return nil, fmt.Errorf("zero or multiple aio bdevs with name %s found", name)
+	}
+	return &bdevAioList[0], nil
+}
+
+func (c *Client) getLvstore(name string) (*spdktypes.LvstoreInfo, error) {
+	lvsList, err := c.BdevLvolGetLvstore(name, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(lvsList) != 1 {
+		return nil, fmt.Errorf("zero or multiple lvstores with name %s found", name)
+	}
+	return &lvsList[0], nil
+}
+
+// GetLvstoreUsage reports the provisioned and allocated space of the logical volume stores.
+//
+//	"lvsName": Optional. Name of the logical volume store. Specify this or "uuid", or neither to report all lvstores.
+//
+//	"uuid": Optional. UUID of the logical volume store.
+//
+//	"topN": Optional. The number of top consumers in each report. 0 means listing all lvols.
+func (c *Client) GetLvstoreUsage(lvsName, uuid string, topN int) (usageList []*spdktypes.LvstoreUsage, err error) {
+	lvsList, err := c.BdevLvolGetLvstore(lvsName, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip the xattr lookups of BdevLvolGet since only the sizes are needed
+	bdevInfoList, err := c.BdevGetBdevs("", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	usageList = []*spdktypes.LvstoreUsage{}
+	for idx := range lvsList {
+		usageList = append(usageList, spdktypes.GetLvstoreUsage(&lvsList[idx], bdevInfoList, topN))
+	}
+
+	return usageList, nil
+}
+
+const (
+	DefaultRaidRebuildPollInterval = time.Second
+)
+
+// RaidRebuildProgress is the progress of the rebuild started by ReplaceBaseBdev.
+type RaidRebuildProgress struct {
+	RaidName       string    `json:"raid_name"`
+	TargetBaseBdev string    `json:"target_base_bdev"`
+	Blocks         uint64    `json:"blocks"`
+	Percent        float64   `json:"percent"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// ReplaceBaseBdev removes a base bdev from a raid bdev, adds a new one, then waits for the rebuild targeting the new one to complete.
+// The rebuild is complete once bdev_raid_get_bdevs reports the raid bdev online and the new base bdev configured without
+// a rebuild process targeting it, since SPDK starts the rebuild as it configures the new base bdev of an online raid bdev.
+// It fails if the raid bdev goes offline, or if the new base bdev is removed or deconfigured by a failed rebuild.
+//
+//	"raidName": Required. The RAID bdev name.
+//
+//	"oldBaseBdevName": Optional. The base bdev to be removed. Leave it empty if the raid bdev already has an empty slot, e.g., after the base bdev is gone.
+//
+//	"newBaseBdevName": Required. The base bdev to be added.
+//
+//	"pollInterval": Optional. How often the rebuild progress is checked. DefaultRaidRebuildPollInterval by default.
+//
+//	"progressCh": Optional. The progress is sent to it on every check, and a final progress of 100 percent is sent once the rebuild completes,
+//	so the caller must keep receiving from it. It is not closed by this function.
+func (c *Client) ReplaceBaseBdev(ctx context.Context, raidName, oldBaseBdevName, newBaseBdevName string, pollInterval time.Duration, progressCh chan<- RaidRebuildProgress) error {
+	if raidName == "" || newBaseBdevName == "" {
+		return fmt.Errorf("empty raid name or new base bdev name for base bdev replacement")
+	}
+	if pollInterval <= 0 {
+		pollInterval = DefaultRaidRebuildPollInterval
+	}
+
+	raid, err := c.getRaidInfo(raidName)
+	if err != nil {
+		return err
+	}
+	if oldBaseBdevName != "" && getBaseBdev(raid, oldBaseBdevName) != nil {
+		if _, err := c.BdevRaidRemoveBaseBdev(oldBaseBdevName); err != nil {
+			return err
+		}
+	}
+	if getBaseBdev(raid, newBaseBdevName) == nil {
+		if _, err := c.BdevRaidAddBaseBdev(raidName, newBaseBdevName); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	rebuildSeen := false
+	for {
+		if raid, err = c.getRaidInfo(raidName); err != nil {
+			return err
+		}
+		if raid.State == spdktypes.BdevRaidCategoryOffline {
+			return fmt.Errorf("raid bdev %s becomes offline during the rebuild of base bdev %s", raidName, newBaseBdevName)
+		}
+		baseBdev := getBaseBdev(raid, newBaseBdevName)
+		if baseBdev == nil {
+			return fmt.Errorf("base bdev %s is gone from raid bdev %s during the rebuild", newBaseBdevName, raidName)
+		}
+
+		rebuilding := raid.Process != nil && raid.Process.Type == spdktypes.BdevRaidProcessTypeRebuild && raid.Process.Target == newBaseBdevName
+		switch {
+		case rebuilding:
+			rebuildSeen = true
+		case !baseBdev.IsConfigured:
+			if rebuildSeen {
+				return fmt.Errorf("rebuild of base bdev %s in raid bdev %s failed", newBaseBdevName, raidName)
+			}
+		case raid.State == spdktypes.BdevRaidCategoryOnline:
+			logrus.Infof("Base bdev %s of raid bdev %s is rebuilt", newBaseBdevName, raidName)
+			return sendRaidRebuildProgress(ctx, progressCh, RaidRebuildProgress{
+				RaidName:       raidName,
+				TargetBaseBdev: newBaseBdevName,
+				Blocks:         baseBdev.DataSize,
+				Percent:        100,
+				Timestamp:      time.Now(),
+			})
+		}
+
+		if rebuilding {
+			if err := sendRaidRebuildProgress(ctx, progressCh, RaidRebuildProgress{
+				RaidName:       raidName,
+				TargetBaseBdev: newBaseBdevName,
+				Blocks:         raid.Process.Progress.Blocks,
+				Percent:        raid.Process.Progress.Percent,
+				Timestamp:      time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func sendRaidRebuildProgress(ctx context.Context, progressCh chan<- RaidRebuildProgress, progress RaidRebuildProgress) error {
+	if progressCh == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case progressCh <- progress:
+		return nil
+	}
+}
+
+// getRaidInfo returns the raid bdev reported by bdev_raid_get_bdevs.
+func (c *Client) getRaidInfo(name string) (*spdktypes.BdevRaidInfo, error) {
+	raidList, err := c.BdevRaidGetInfoByCategory(spdktypes.BdevRaidCategoryAll)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range raidList {
+		if raidList[idx].Name == name {
+			return &raidList[idx], nil
+		}
+	}
+	return nil, fmt.Errorf("cannot find raid bdev %s", name)
+}
+
+func getBaseBdev(raid *spdktypes.BdevRaidInfo, baseBdevName string) *spdktypes.BaseBdev {
+	for idx := range raid.BaseBdevsList {
+		if raid.BaseBdevsList[idx].Name == baseBdevName {
+			return &raid.BaseBdevsList[idx]
+		}
+	}
+	return nil
+}
+
 // StartExposeBdev exposes the bdev with the given nqn, bdevName, nguid, ip, and port.
+// The serial number of the subsystem is derived from the nqn, see types.GetSerialNumber.
 func (c *Client) StartExposeBdev(nqn, bdevName, nguid, ip, port string) error {
-	nvmfTransportList, err := c.NvmfGetTransports("", "")
+	return c.StartExposeBdevWithOptions(StartExposeBdevOptions{
+		Nqn:      nqn,
+		BdevName: bdevName,
+		Nguid:    nguid,
+		IP:       ip,
+		Port:     port,
+	})
+}
+
+// StartExposeBdevOptions are the options of StartExposeBdevWithOptions.
+// See StartExposeBdev for the meaning of the basic options.
+type StartExposeBdevOptions struct {
+	Nqn      string
+	BdevName string
+	Nguid    string
+	IP       string
+	Port     string
+
+	// TransportOptions is optional. It is used only if the TCP transport does not exist yet.
+	TransportOptions *NvmfCreateTransportOptions
+}
+
+// StartExposeBdevWithOptions exposes the bdev with the options.
+func (c *Client) StartExposeBdevWithOptions(opts StartExposeBdevOptions) error {
+	if err := c.ensureNvmfTransport(opts.TransportOptions); err != nil {
+		return err
+	}
+
+	if _, err := c.NvmfCreateSubsystemWithOptions(NvmfCreateSubsystemOptions{
+		Nqn:          opts.Nqn,
+		AllowAnyHost: true,
+		SerialNumber: types.GetSerialNumber(opts.Nqn),
+	}); err != nil {
+		return err
+	}
+
+	if _, err := c.NvmfSubsystemAddNs(opts.Nqn, opts.BdevName, opts.Nguid); err != nil {
+		return err
+	}
+
+	if _, err := c.NvmfSubsystemAddListener(opts.Nqn, opts.IP, opts.Port, spdktypes.NvmeTransportTypeTCP, ""); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) ensureNvmfTransport(transportOpts *NvmfCreateTransportOptions) error {
+	opts := NvmfCreateTransportOptions{}
+	if transportOpts != nil {
+		opts = *transportOpts
+	}
+	if opts.Trtype == "" {
+		opts.Trtype = spdktypes.NvmeTransportTypeTCP
+	}
+	if opts.Trtype != spdktypes.NvmeTransportTypeTCP {
+		return fmt.Errorf("unsupported transport type %s for exposing bdev", opts.Trtype)
+	}
+
+	nvmfTransportList, err := c.NvmfGetTransports("", opts.TgtName)
 	if err != nil {
 		return err
 	}
 	if nvmfTransportList != nil && len(nvmfTransportList) == 0 {
-		if _, err := c.NvmfCreateTransport(spdktypes.NvmeTransportTypeTCP); err != nil && !jsonrpc.IsJSONRPCRespErrorTransportTypeAlreadyExists(err) {
+		if _, err := c.NvmfCreateTransportWithOptions(opts); err != nil && !jsonrpc.IsJSONRPCRespErrorTransportTypeAlreadyExists(err) {
+			return err
+		}
+	} else if transportOpts != nil {
+		logrus.Warnf("Ignored the transport options for exposing bdev since the nvmf transport already exists")
+	}
+	return nil
+}
+
+// StartDiscoveryService makes the discovery subsystem listen on the ip and port, so that a host can find all subsystems
+// exposed by this target from one well-known endpoint rather than knowing the port of each subsystem in advance.
+// It is a no-op if the discovery subsystem already listens on the address.
+func (c *Client) StartDiscoveryService(ip, port string) error {
+	if err := c.ensureNvmfTransport(nil); err != nil {
+		return err
+	}
+
+	listenerList, err := c.NvmfSubsystemGetListeners(spdktypes.NvmfDiscoveryNqn, "")
+	if err != nil {
+		return err
+	}
+	for _, l := range listenerList {
+		if isListenAddress(l.Address, ip, port) {
+			return nil
+		}
+	}
+
+	if _, err := c.NvmfSubsystemAddListener(spdktypes.NvmfDiscoveryNqn, ip, port, spdktypes.NvmeTransportTypeTCP, ""); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// StopDiscoveryService stops the discovery subsystem listening on the ip and port.
+func (c *Client) StopDiscoveryService(ip, port string) error {
+	listenerList, err := c.NvmfSubsystemGetListeners(spdktypes.NvmfDiscoveryNqn, "")
+	if err != nil {
+		return err
+	}
+	for _, l := range listenerList {
+		if !isListenAddress(l.Address, ip, port) {
+			continue
+		}
+		if _, err := c.NvmfSubsystemRemoveListener(spdktypes.NvmfDiscoveryNqn, l.Address.Traddr, l.Address.Trsvcid, l.Address.Trtype, l.Address.Adrfam); err != nil {
 			return err
 		}
 	}
 
-	if _, err := c.NvmfCreateSubsystem(nqn); err != nil {
+	return nil
+}
+
+// isListenAddress returns true if the listen address is on the ip and port, regardless of the form of the IP literal.
+func isListenAddress(address spdktypes.NvmfSubsystemListenAddress, ip, port string) bool {
+	return types.NormalizeIP(address.Traddr) == types.NormalizeIP(ip) && address.Trsvcid == port
+}
+
+// ExposeListener is a listen address of StartExposeBdevMultipath.
+type ExposeListener struct {
+	IP   string
+	Port string
+	// AnaState is the initial ANA state of the listener. "optimized" by default.
+	AnaState spdktypes.NvmfSubsystemListenerAnaState
+}
+
+// StartExposeBdevMultipath exposes the bdev on multiple listeners with the ANA reporting enabled.
+// A multipath host connecting to all listeners sends I/O through the optimized ones only,
+// hence the ANA states can steer the host from one listener to another, e.g., during a live migration.
+// At least one listener must be optimized.
+func (c *Client) StartExposeBdevMultipath(nqn, bdevName, nguid string, listeners []ExposeListener) error {
+	if len(listeners) == 0 {
+		return fmt.Errorf("no listener for exposing bdev %s with nqn %s", bdevName, nqn)
+	}
+	hasOptimized := false
+	for _, listener := range listeners {
+		if listener.AnaState == "" || listener.AnaState == spdktypes.NvmfSubsystemListenerAnaStateOptimized {
+			hasOptimized = true
+			break
+		}
+	}
+	if !hasOptimized {
+		return fmt.Errorf("no optimized listener for exposing bdev %s with nqn %s", bdevName, nqn)
+	}
+
+	if err := c.ensureNvmfTransport(nil); err != nil {
+		return err
+	}
+
+	if _, err := c.NvmfCreateSubsystemWithOptions(NvmfCreateSubsystemOptions{
+		Nqn:          nqn,
+		AllowAnyHost: true,
+		AnaReporting: true,
+		SerialNumber: types.GetSerialNumber(nqn),
+	}); err != nil {
 		return err
 	}
 
@@ -73,10 +536,57 @@ func (c *Client) StartExposeBdev(nqn, bdevName, nguid, ip, port string) error {
 		return err
 	}
 
-	if _, err := c.NvmfSubsystemAddListener(nqn, ip, port, spdktypes.NvmeTransportTypeTCP, spdktypes.NvmeAddressFamilyIPv4); err != nil {
+	for _, listener := range listeners {
+		anaState := listener.AnaState
+		if anaState == "" {
+			anaState = spdktypes.NvmfSubsystemListenerAnaStateOptimized
+		}
+		if _, err := c.NvmfSubsystemAddListener(nqn, listener.IP, listener.Port, spdktypes.NvmeTransportTypeTCP, ""); err != nil {
+			return err
+		}
+		if _, err := c.NvmfSubsystemListenerSetAnaState(nqn, listener.IP, listener.Port, spdktypes.NvmeTransportTypeTCP, "", anaState, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetExposeBdevOptimizedListener makes the listener with the given ip and port the only optimized one of the subsystem,
+// and makes the others inaccessible. The listener becomes optimized before the others become inaccessible,
+// so that the multipath hosts always have a usable path.
+func (c *Client) SetExposeBdevOptimizedListener(nqn, ip, port string) error {
+	listenerList, err := c.NvmfSubsystemGetListeners(nqn, "")
+	if err != nil {
+		return err
+	}
+
+	var optimized *spdktypes.NvmfSubsystemListener
+	for idx := range listenerList {
+		if isListenAddress(listenerList[idx].Address, ip, port) {
+			optimized = &listenerList[idx]
+			break
+		}
+	}
+	if optimized == nil {
+		return fmt.Errorf("cannot find listener %s:%s of subsystem %s", ip, port, nqn)
+	}
+
+	if _, err := c.NvmfSubsystemListenerSetAnaState(nqn, ip, port, optimized.Address.Trtype, optimized.Address.Adrfam,
+		spdktypes.NvmfSubsystemListenerAnaStateOptimized, 0); err != nil {
 		return err
 	}
 
+	for _, l := range listenerList {
+		if isListenAddress(l.Address, ip, port) {
+			continue
+		}
+		if _, err := c.NvmfSubsystemListenerSetAnaState(nqn, l.Address.Traddr, l.Address.Trsvcid, l.Address.Trtype, l.Address.Adrfam,
+			spdktypes.NvmfSubsystemListenerAnaStateInaccessible, 0); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -121,3 +631,236 @@ func (c *Client) StopExposeBdev(nqn string) error {
 
 	return nil
 }
+
+// SnapshotConsistencyGroup takes a crash-consistent snapshot of the lvols behind the namespaces of the subsystems.
+// All subsystems are paused before the first snapshot and are always resumed, even if a snapshot fails.
+// The snapshot of each lvol is named <LVOL NAME>-<snapshotName> and shares the xattrs.
+// It returns the UUIDs of the created snapshots keyed by the lvol aliases, which may be partial on error.
+func (c *Client) SnapshotConsistencyGroup(nqns []string, snapshotName string, xattrs []Xattr) (snapshots map[string]string, err error) {
+	if len(nqns) == 0 {
+		return nil, fmt.Errorf("no subsystem for consistency group snapshot %s", snapshotName)
+	}
+
+	// Resolve all lvols before pausing any subsystem so that an invalid group does not disturb the I/O
+	lvolAliases := []string{}
+	for _, nqn := range nqns {
+		subsystemList, err := c.NvmfGetSubsystems(nqn, "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get subsystem %s for consistency group snapshot %s", nqn, snapshotName)
+		}
+		if len(subsystemList) != 1 {
+			return nil, fmt.Errorf("zero or multiple subsystems with nqn %s found", nqn)
+		}
+		for _, ns := range subsystemList[0].Namespaces {
+			bdevList, err := c.BdevGetBdevs(ns.BdevName, 0)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get bdev %s of subsystem %s for consistency group snapshot %s", ns.BdevName, nqn, snapshotName)
+			}
+			if len(bdevList) != 1 || spdktypes.GetBdevType(&bdevList[0]) != spdktypes.BdevTypeLvol || len(bdevList[0].Aliases) == 0 {
+				return nil, fmt.Errorf("bdev %s of subsystem %s is not an lvol", ns.BdevName, nqn)
+			}
+			lvolAliases = append(lvolAliases, bdevList[0].Aliases[0])
+		}
+	}
+
+	pausedNqns := []string{}
+	defer func() {
+		for _, nqn := range pausedNqns {
+			if _, resumeErr := c.NvmfSubsystemResume(nqn); resumeErr != nil {
+				resumeErr = errors.Wrapf(resumeErr, "failed to resume subsystem %s after consistency group snapshot %s", nqn, snapshotName)
+				if err == nil {
+					err = resumeErr
+				} else {
+					logrus.WithError(resumeErr).Error("Failed to resume subsystem")
+				}
+			}
+		}
+	}()
+
+	for _, nqn := range nqns {
+		if _, err := c.NvmfSubsystemPause(nqn, 0); err != nil {
+			return nil, errors.Wrapf(err, "failed to pause subsystem %s for consistency group snapshot %s", nqn, snapshotName)
+		}
+		pausedNqns = append(pausedNqns, nqn)
+	}
+
+	snapshots = map[string]string{}
+	for _, lvolAlias := range lvolAliases {
+		lvolSnapshotName := fmt.Sprintf("%s-%s", spdktypes.GetLvolNameFromAlias(lvolAlias), snapshotName)
+		uuid, err := c.BdevLvolSnapshot(lvolAlias, lvolSnapshotName, xattrs)
+		if err != nil {
+			return snapshots, errors.Wrapf(err, "failed to snapshot lvol %s for consistency group snapshot %s", lvolAlias, snapshotName)
+		}
+		snapshots[lvolAlias] = uuid
+	}
+
+	return snapshots, nil
+}
+
+// SubsystemConnection is a host connected to an NVMe-oF subsystem, i.e., a controller of the subsystem.
+type SubsystemConnection struct {
+	Nqn     string `json:"nqn"`
+	HostNQN string `json:"host_nqn"`
+	HostID  string `json:"host_id"`
+	Cntlid  uint16 `json:"cntlid"`
+	// HostAddress is empty if the SPDK version does not report the peer addresses of the qpairs
+	HostAddress   string `json:"host_address,omitempty"`
+	ListenAddress string `json:"listen_address,omitempty"`
+
+	NumAdminQpairs uint32 `json:"num_admin_qpairs"`
+	NumIoQpairs    uint32 `json:"num_io_qpairs"`
+}
+
+// GetSubsystemConnections lists the hosts connected to the subsystem.
+// An empty list means no host, e.g., an engine, is connected, hence it is safe to stop exposing the subsystem.
+func (c *Client) GetSubsystemConnections(nqn string) ([]SubsystemConnection, error) {
+	controllerList, err := c.NvmfSubsystemGetControllers(nqn, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(controllerList) == 0 {
+		return []SubsystemConnection{}, nil
+	}
+
+	qpairList, err := c.NvmfSubsystemGetQpairs(nqn, "")
+	if err != nil {
+		return nil, err
+	}
+
+	connections := []SubsystemConnection{}
+	for _, ctrlr := range controllerList {
+		conn := SubsystemConnection{
+			Nqn:     nqn,
+			HostNQN: ctrlr.Hostnqn,
+			HostID:  ctrlr.Hostid,
+			Cntlid:  ctrlr.Cntlid,
+		}
+		for _, qpair := range qpairList {
+			if qpair.Cntlid != ctrlr.Cntlid {
+				continue
+			}
+			if qpair.Qid == 0 {
+				conn.NumAdminQpairs++
+			} else {
+				conn.NumIoQpairs++
+			}
+			if conn.ListenAddress == "" {
+				conn.ListenAddress = net.JoinHostPort(qpair.ListenAddress.Traddr, qpair.ListenAddress.Trsvcid)
+			}
+			if conn.HostAddress == "" && qpair.PeerAddress != nil {
+				conn.HostAddress = net.JoinHostPort(qpair.PeerAddress.Traddr, qpair.PeerAddress.Trsvcid)
+			}
+		}
+		connections = append(connections, conn)
+	}
+
+	return connections, nil
+}
+
+// SetSubsystemHosts makes the hosts the only ones allowed to connect to the subsystem.
+// The hosts not in the list are removed, and a host whose TLS or DH-HMAC-CHAP keys change is re-added with the new keys.
+// Since re-adding a host disconnects its controllers, a key is compared only if nvmf_get_subsystems reports it.
+// An empty list allows any host to connect.
+func (c *Client) SetSubsystemHosts(nqn string, hosts []spdktypes.NvmfSubsystemHost) error {
+	subsystemList, err := c.NvmfGetSubsystems(nqn, "")
+	if err != nil {
+		return err
+	}
+	if len(subsystemList) != 1 {
+		return fmt.Errorf("zero or multiple subsystems with nqn %s found", nqn)
+	}
+	subsystem := subsystemList[0]
+
+	if len(hosts) == 0 {
+		if !subsystem.AllowAnyHost {
+			if _, err := c.NvmfSubsystemAllowAnyHost(nqn, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	expected := map[string]spdktypes.NvmfSubsystemHost{}
+	for _, host := range hosts {
+		expected[host.Nqn] = host
+	}
+	existing := map[string]bool{}
+	for _, host := range subsystem.Hosts {
+		if expectedHost, ok := expected[host.Nqn]; ok && !isSubsystemHostKeyChanged(host, expectedHost) {
+			existing[host.Nqn] = true
+			continue
+		}
+		if _, err := c.NvmfSubsystemRemoveHost(nqn, host.Nqn); err != nil {
+			return err
+		}
+	}
+	for _, host := range hosts {
+		if existing[host.Nqn] {
+			continue
+		}
+		if _, err := c.NvmfSubsystemAddHostWithOptions(NvmfSubsystemAddHostOptions{
+			Nqn:            nqn,
+			HostNQN:        host.Nqn,
+			Psk:            host.Psk,
+			DhchapKey:      host.DhchapKey,
+			DhchapCtrlrKey: host.DhchapCtrlrKey,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Disable the allow any host after the hosts are added so that the allowed hosts are never rejected
+	if subsystem.AllowAnyHost {
+		if _, err := c.NvmfSubsystemAllowAnyHost(nqn, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isSubsystemHostKeyChanged returns true if a key reported for the host differs from the expected one.
+// The keys SPDK does not report are left out, otherwise the host would be re-added on every call.
+func isSubsystemHostKeyChanged(reported, expected spdktypes.NvmfSubsystemHost) bool {
+	return (reported.Psk != "" && reported.Psk != expected.Psk) ||
+		(reported.DhchapKey != "" && reported.DhchapKey != expected.DhchapKey) ||
+		(reported.DhchapCtrlrKey != "" && reported.DhchapCtrlrKey != expected.DhchapCtrlrKey)
+}
+
+// SwitchBdevNvmeActivePath makes the path to the target with the given ip and port the active one of the bdev NVMe
+// attached via multiple paths, e.g., before a planned maintenance of the replica behind the current path.
+// The multipath policy of the bdev is set to "active_passive", so that all I/O goes through the preferred path.
+// The new path must be connected and accessible, otherwise the bdev is left as it is.
+func (c *Client) SwitchBdevNvmeActivePath(bdevName, ip, port string) error {
+	ioPaths, err := c.BdevNvmeGetIoPaths(bdevName)
+	if err != nil {
+		return err
+	}
+
+	var target *spdktypes.BdevNvmeIoPath
+	for _, group := range ioPaths.PollGroups {
+		for idx := range group.IoPaths {
+			path := &group.IoPaths[idx]
+			if path.BdevName != bdevName || types.NormalizeIP(path.Transport.Traddr) != types.NormalizeIP(ip) || path.Transport.Trsvcid != port {
+				continue
+			}
+			if !path.Connected || !path.Accessible {
+				return fmt.Errorf("path %s of bdev %s is not usable, connected: %v, accessible: %v",
+					net.JoinHostPort(ip, port), bdevName, path.Connected, path.Accessible)
+			}
+			target = path
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("cannot find path %s of bdev %s", net.JoinHostPort(ip, port), bdevName)
+	}
+
+	if _, err := c.BdevNvmeSetMultipathPolicy(bdevName, spdktypes.BdevNvmeMultipathPolicyActivePassive, "", 0); err != nil {
+		return errors.Wrapf(err, "failed to set multipath policy of bdev %s", bdevName)
+	}
+	if _, err := c.BdevNvmeSetPreferredPath(bdevName, target.Cntlid); err != nil {
+		return errors.Wrapf(err, "failed to set path %s as the preferred one of bdev %s", net.JoinHostPort(ip, port), bdevName)
+	}
+
+	return nil
+}
@@ -0,0 +1,757 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/types"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+// setAnaState accepts the ANA states the way SPDK parses them, so that a
+// state is checked against the literal string sent on the wire.
+func setAnaState(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+	req := struct {
+		AnaState string `json:"ana_state"`
+	}{}
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+	}
+	switch req.AnaState {
+	case "optimized", "non_optimized", "inaccessible":
+		return true, nil
+	default:
+		return nil, &jsonrpc.ResponseError{Code: -32602, Message: "Invalid parameters"}
+	}
+}
+
+func (s *TestSuite) TestStartExposeBdevMultipath(c *C) {
+	server := newFakeServer(map[string]fakeHandler{
+		"nvmf_get_transports": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.NvmfTransport{{Trtype: spdktypes.NvmeTransportTypeTCP}}, nil
+		},
+		"nvmf_subsystem_add_ns": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return 1, nil
+		},
+		"nvmf_subsystem_listener_set_ana_state": setAnaState,
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	nqn := "nqn.2023-01.io.longhorn.spdk:vol"
+	err := spdkCli.StartExposeBdevMultipath(nqn, "lvs/vol", "", []ExposeListener{
+		{IP: "10.0.0.1", Port: "20001"},
+		{IP: "10.0.0.2", Port: "20001", AnaState: spdktypes.NvmfSubsystemListenerAnaStateInaccessible},
+		{IP: "10.0.0.3", Port: "20001", AnaState: spdktypes.NvmfSubsystemListenerAnaStateNonOptimized},
+	})
+	c.Assert(err, IsNil)
+
+	c.Assert(server.methods(), DeepEquals, []string{
+		"nvmf_get_transports",
+		"nvmf_create_subsystem",
+		"nvmf_subsystem_add_ns",
+		"nvmf_subsystem_add_listener",
+		"nvmf_subsystem_listener_set_ana_state",
+		"nvmf_subsystem_add_listener",
+		"nvmf_subsystem_listener_set_ana_state",
+		"nvmf_subsystem_add_listener",
+		"nvmf_subsystem_listener_set_ana_state",
+	})
+
+	createReqs := []spdktypes.NvmfCreateSubsystemRequest{}
+	server.requestsOf(c, "nvmf_create_subsystem", &createReqs)
+	c.Assert(createReqs[0].Nqn, Equals, nqn)
+	c.Assert(createReqs[0].AnaReporting, Equals, true)
+	c.Assert(createReqs[0].SerialNumber, Equals, types.GetSerialNumber(nqn))
+	c.Assert(len(createReqs[0].SerialNumber), Equals, types.NvmfSerialNumberLength)
+
+	anaReqs := []spdktypes.NvmfSubsystemListenerSetAnaStateRequest{}
+	server.requestsOf(c, "nvmf_subsystem_listener_set_ana_state", &anaReqs)
+	c.Assert(len(anaReqs), Equals, 3)
+	c.Assert(anaReqs[0].ListenAddress.Traddr, Equals, "10.0.0.1")
+	c.Assert(string(anaReqs[0].AnaState), Equals, "optimized")
+	c.Assert(anaReqs[1].ListenAddress.Traddr, Equals, "10.0.0.2")
+	c.Assert(string(anaReqs[1].AnaState), Equals, "inaccessible")
+	c.Assert(anaReqs[2].ListenAddress.Traddr, Equals, "10.0.0.3")
+	c.Assert(string(anaReqs[2].AnaState), Equals, "non_optimized")
+
+	// Exposing without listeners or without an optimized listener makes no sense
+	methodCount := len(server.methods())
+	err = spdkCli.StartExposeBdevMultipath(nqn, "lvs/vol", "", nil)
+	c.Assert(err, NotNil)
+	err = spdkCli.StartExposeBdevMultipath(nqn, "lvs/vol", "", []ExposeListener{
+		{IP: "10.0.0.1", Port: "20001", AnaState: spdktypes.NvmfSubsystemListenerAnaStateInaccessible},
+		{IP: "10.0.0.2", Port: "20001", AnaState: spdktypes.NvmfSubsystemListenerAnaStateNonOptimized},
+	})
+	c.Assert(err, NotNil)
+	c.Assert(len(server.methods()), Equals, methodCount)
+}
+
+func (s *TestSuite) TestSetExposeBdevOptimizedListener(c *C) {
+	listeners := []spdktypes.NvmfSubsystemListener{}
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		listeners = append(listeners, spdktypes.NvmfSubsystemListener{
+			Address: spdktypes.NvmfSubsystemListenAddress{
+				Trtype:  spdktypes.NvmeTransportTypeTCP,
+				Adrfam:  spdktypes.NvmeAddressFamilyIPv4,
+				Traddr:  ip,
+				Trsvcid: "20001",
+			},
+		})
+	}
+	server := newFakeServer(map[string]fakeHandler{
+		"nvmf_subsystem_get_listeners": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return listeners, nil
+		},
+		"nvmf_subsystem_listener_set_ana_state": setAnaState,
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	nqn := "nqn.2023-01.io.longhorn.spdk:vol"
+	err := spdkCli.SetExposeBdevOptimizedListener(nqn, "10.0.0.2", "20001")
+	c.Assert(err, IsNil)
+
+	// The new optimized listener goes first so that the hosts never lose all paths
+	anaReqs := []spdktypes.NvmfSubsystemListenerSetAnaStateRequest{}
+	server.requestsOf(c, "nvmf_subsystem_listener_set_ana_state", &anaReqs)
+	c.Assert(len(anaReqs), Equals, 3)
+	c.Assert(anaReqs[0].ListenAddress.Traddr, Equals, "10.0.0.2")
+	c.Assert(string(anaReqs[0].AnaState), Equals, "optimized")
+	for _, req := range anaReqs[1:] {
+		c.Assert(req.ListenAddress.Traddr, Not(Equals), "10.0.0.2")
+		c.Assert(string(req.AnaState), Equals, "inaccessible")
+	}
+
+	err = spdkCli.SetExposeBdevOptimizedListener(nqn, "10.0.0.4", "20001")
+	c.Assert(err, ErrorMatches, "cannot find listener .*")
+}
+
+func (s *TestSuite) TestGetSubsystemConnections(c *C) {
+	listenAddress := spdktypes.NvmfSubsystemListenAddress{
+		Trtype:  spdktypes.NvmeTransportTypeTCP,
+		Adrfam:  spdktypes.NvmeAddressFamilyIPv4,
+		Traddr:  "10.0.0.1",
+		Trsvcid: "20001",
+	}
+	peerAddress := spdktypes.NvmfSubsystemListenAddress{
+		Trtype:  spdktypes.NvmeTransportTypeTCP,
+		Adrfam:  spdktypes.NvmeAddressFamilyIPv4,
+		Traddr:  "10.0.0.9",
+		Trsvcid: "51234",
+	}
+	server := newFakeServer(map[string]fakeHandler{
+		"nvmf_subsystem_get_controllers": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.NvmfSubsystemController{
+				{Cntlid: 1, Hostnqn: "nqn.2014-08.org.nvmexpress:uuid:engine-a", Hostid: "a", NumIoQpairs: 2},
+				{Cntlid: 2, Hostnqn: "nqn.2014-08.org.nvmexpress:uuid:engine-b", Hostid: "b", NumIoQpairs: 1},
+			}, nil
+		},
+		"nvmf_subsystem_get_qpairs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.NvmfSubsystemQpair{
+				{Cntlid: 1, Qid: 0, State: "active", ListenAddress: listenAddress, PeerAddress: &peerAddress},
+				{Cntlid: 1, Qid: 1, State: "active", ListenAddress: listenAddress, PeerAddress: &peerAddress},
+				{Cntlid: 1, Qid: 2, State: "active", ListenAddress: listenAddress, PeerAddress: &peerAddress},
+				{Cntlid: 2, Qid: 0, State: "active", ListenAddress: listenAddress},
+				{Cntlid: 2, Qid: 1, State: "active", ListenAddress: listenAddress},
+			}, nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	nqn := "nqn.2023-01.io.longhorn.spdk:vol"
+	connections, err := spdkCli.GetSubsystemConnections(nqn)
+	c.Assert(err, IsNil)
+	c.Assert(connections, DeepEquals, []SubsystemConnection{
+		{
+			Nqn:            nqn,
+			HostNQN:        "nqn.2014-08.org.nvmexpress:uuid:engine-a",
+			HostID:         "a",
+			Cntlid:         1,
+			HostAddress:    "10.0.0.9:51234",
+			ListenAddress:  "10.0.0.1:20001",
+			NumAdminQpairs: 1,
+			NumIoQpairs:    2,
+		},
+		{
+			Nqn:            nqn,
+			HostNQN:        "nqn.2014-08.org.nvmexpress:uuid:engine-b",
+			HostID:         "b",
+			Cntlid:         2,
+			ListenAddress:  "10.0.0.1:20001",
+			NumAdminQpairs: 1,
+			NumIoQpairs:    1,
+		},
+	})
+}
+
+func (s *TestSuite) TestSnapshotConsistencyGroup(c *C) {
+	lvols := map[string]spdktypes.BdevInfo{}
+	for _, alias := range []string{"lvs/vol1-r-0", "lvs/vol2-r-0"} {
+		lvols[alias] = spdktypes.BdevInfo{
+			BdevInfoBasic: spdktypes.BdevInfoBasic{
+				Name:        alias,
+				Aliases:     []string{alias},
+				ProductName: spdktypes.BdevProductNameLvol,
+			},
+			DriverSpecific: &spdktypes.BdevDriverSpecific{
+				Lvol: &spdktypes.BdevDriverSpecificLvol{},
+			},
+		}
+	}
+	nqns := []string{"nqn.2023-01.io.longhorn.spdk:vol1", "nqn.2023-01.io.longhorn.spdk:vol2"}
+
+	newServer := func(failedLvol string) *fakeServer {
+		return newFakeServer(map[string]fakeHandler{
+			"nvmf_get_subsystems": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+				req := spdktypes.NvmfGetSubsystemsRequest{}
+				c.Assert(json.Unmarshal(params, &req), IsNil)
+				bdevName := "lvs/vol1-r-0"
+				if req.Nqn == nqns[1] {
+					bdevName = "lvs/vol2-r-0"
+				}
+				return []spdktypes.NvmfSubsystem{{
+					Nqn:        req.Nqn,
+					Namespaces: []spdktypes.NvmfSubsystemNamespace{{Nsid: 1, BdevName: bdevName}},
+				}}, nil
+			},
+			"bdev_get_bdevs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+				req := spdktypes.BdevGetBdevsRequest{}
+				c.Assert(json.Unmarshal(params, &req), IsNil)
+				return []spdktypes.BdevInfo{lvols[req.Name]}, nil
+			},
+			"bdev_lvol_snapshot": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+				req := spdktypes.BdevLvolSnapshotRequest{}
+				c.Assert(json.Unmarshal(params, &req), IsNil)
+				if req.LvolName == failedLvol {
+					return nil, &jsonrpc.ResponseError{Code: -32602, Message: "No space left on device"}
+				}
+				return req.SnapshotName + "-uuid", nil
+			},
+		})
+	}
+
+	server := newServer("")
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	snapshots, err := spdkCli.SnapshotConsistencyGroup(nqns, "snap1", []Xattr{{Name: "group", Value: "cg1"}})
+	c.Assert(err, IsNil)
+	c.Assert(snapshots, DeepEquals, map[string]string{
+		"lvs/vol1-r-0": "vol1-r-0-snap1-uuid",
+		"lvs/vol2-r-0": "vol2-r-0-snap1-uuid",
+	})
+	c.Assert(server.methods()[4:], DeepEquals, []string{
+		"nvmf_subsystem_pause",
+		"nvmf_subsystem_pause",
+		"bdev_lvol_snapshot",
+		"bdev_lvol_snapshot",
+		"nvmf_subsystem_resume",
+		"nvmf_subsystem_resume",
+	})
+	snapshotReqs := []spdktypes.BdevLvolSnapshotRequest{}
+	server.requestsOf(c, "bdev_lvol_snapshot", &snapshotReqs)
+	for _, req := range snapshotReqs {
+		c.Assert(req.Xattrs, DeepEquals, map[string]string{"group": "cg1"})
+	}
+
+	// The subsystems are resumed even if a snapshot fails
+	failedServer := newServer("lvs/vol1-r-0")
+	failedCli, failedCloseFn := failedServer.newClient()
+	defer failedCloseFn()
+
+	snapshots, err = failedCli.SnapshotConsistencyGroup(nqns, "snap2", nil)
+	c.Assert(err, ErrorMatches, "failed to snapshot lvol lvs/vol1-r-0 .*")
+	c.Assert(snapshots, DeepEquals, map[string]string{})
+	c.Assert(failedServer.methods()[4:], DeepEquals, []string{
+		"nvmf_subsystem_pause",
+		"nvmf_subsystem_pause",
+		"bdev_lvol_snapshot",
+		"nvmf_subsystem_resume",
+		"nvmf_subsystem_resume",
+	})
+}
+
+func (s *TestSuite) TestStartDiscoveryService(c *C) {
+	listeners := []spdktypes.NvmfSubsystemListener{}
+	server := newFakeServer(map[string]fakeHandler{
+		"nvmf_get_transports": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.NvmfTransport{{Trtype: spdktypes.NvmeTransportTypeTCP}}, nil
+		},
+		"nvmf_subsystem_get_listeners": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return listeners, nil
+		},
+		"nvmf_subsystem_add_listener": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.NvmfSubsystemAddListenerRequest{}
+			c.Assert(json.Unmarshal(params, &req), IsNil)
+			listeners = append(listeners, spdktypes.NvmfSubsystemListener{Address: req.ListenAddress})
+			return true, nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	// Starting twice adds the discovery listener once
+	for i := 0; i < 2; i++ {
+		err := spdkCli.StartDiscoveryService("10.0.0.1", "8009")
+		c.Assert(err, IsNil)
+	}
+
+	addReqs := []spdktypes.NvmfSubsystemAddListenerRequest{}
+	server.requestsOf(c, "nvmf_subsystem_add_listener", &addReqs)
+	c.Assert(len(addReqs), Equals, 1)
+	c.Assert(addReqs[0].Nqn, Equals, spdktypes.NvmfDiscoveryNqn)
+	c.Assert(addReqs[0].ListenAddress.Traddr, Equals, "10.0.0.1")
+	c.Assert(addReqs[0].ListenAddress.Trsvcid, Equals, "8009")
+}
+
+func (s *TestSuite) TestSetSubsystemHosts(c *C) {
+	nqn := "nqn.2023-01.io.longhorn.spdk:vol"
+	testCases := []struct {
+		name     string
+		reported []spdktypes.NvmfSubsystemHost
+		removed  []string
+		added    []string
+	}{
+		{
+			// SPDK may not report the key names, which must not be taken as a key change
+			"keys not reported",
+			[]spdktypes.NvmfSubsystemHost{{Nqn: "host1"}, {Nqn: "host2"}},
+			[]string{},
+			[]string{},
+		},
+		{
+			"keys reported",
+			[]spdktypes.NvmfSubsystemHost{{Nqn: "host1", Psk: "psk1"}, {Nqn: "host2", DhchapKey: "key2", DhchapCtrlrKey: "ctrlr-key2"}},
+			[]string{},
+			[]string{},
+		},
+		{
+			"key changed",
+			[]spdktypes.NvmfSubsystemHost{{Nqn: "host1", Psk: "old-psk1"}, {Nqn: "host2", DhchapKey: "key2"}},
+			[]string{"host1"},
+			[]string{"host1"},
+		},
+		{
+			"hosts changed",
+			[]spdktypes.NvmfSubsystemHost{{Nqn: "host1"}, {Nqn: "host3"}},
+			[]string{"host3"},
+			[]string{"host2"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		comment := Commentf("test case %s", testCase.name)
+
+		reported := testCase.reported
+		server := newFakeServer(map[string]fakeHandler{
+			"nvmf_get_subsystems": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+				return []spdktypes.NvmfSubsystem{{Nqn: nqn, Hosts: reported}}, nil
+			},
+		})
+		spdkCli, closeFn := server.newClient()
+
+		err := spdkCli.SetSubsystemHosts(nqn, []spdktypes.NvmfSubsystemHost{
+			{Nqn: "host1", Psk: "psk1"},
+			{Nqn: "host2", DhchapKey: "key2", DhchapCtrlrKey: "ctrlr-key2"},
+		})
+		c.Assert(err, IsNil, comment)
+
+		removeReqs := []spdktypes.NvmfSubsystemRemoveHostRequest{}
+		server.requestsOf(c, "nvmf_subsystem_remove_host", &removeReqs)
+		removed := []string{}
+		for _, req := range removeReqs {
+			removed = append(removed, req.Host)
+		}
+		c.Assert(removed, DeepEquals, testCase.removed, comment)
+
+		addReqs := []spdktypes.NvmfSubsystemAddHostRequest{}
+		server.requestsOf(c, "nvmf_subsystem_add_host", &addReqs)
+		added := []string{}
+		for _, req := range addReqs {
+			added = append(added, req.Host)
+			if req.Host == "host1" {
+				c.Assert(req.Psk, Equals, "psk1", comment)
+			}
+		}
+		c.Assert(added, DeepEquals, testCase.added, comment)
+
+		closeFn()
+	}
+}
+
+func (s *TestSuite) TestStartExposeBdevIPv6(c *C) {
+	server := newFakeServer(map[string]fakeHandler{
+		"nvmf_get_transports": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.NvmfTransport{{Trtype: spdktypes.NvmeTransportTypeTCP}}, nil
+		},
+		"nvmf_subsystem_add_ns": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return 1, nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	for _, ip := range []string{types.LocalIPv6, "[::1]", "0:0:0:0:0:0:0:1"} {
+		err := spdkCli.StartExposeBdev(types.GetNQN("vol"), "lvs/vol", "", ip, "20001")
+		c.Assert(err, IsNil)
+	}
+	err := spdkCli.StartExposeBdev(types.GetNQN("vol"), "lvs/vol", "", types.LocalIP, "20001")
+	c.Assert(err, IsNil)
+
+	addReqs := []spdktypes.NvmfSubsystemAddListenerRequest{}
+	server.requestsOf(c, "nvmf_subsystem_add_listener", &addReqs)
+	c.Assert(len(addReqs), Equals, 4)
+	for _, req := range addReqs[:3] {
+		c.Assert(req.ListenAddress.Traddr, Equals, types.LocalIPv6)
+		c.Assert(req.ListenAddress.Adrfam, Equals, spdktypes.NvmeAddressFamilyIPv6)
+	}
+	c.Assert(addReqs[3].ListenAddress.Traddr, Equals, types.LocalIP)
+	c.Assert(addReqs[3].ListenAddress.Adrfam, Equals, spdktypes.NvmeAddressFamilyIPv4)
+}
+
+func (s *TestSuite) TestSwitchBdevNvmeActivePath(c *C) {
+	newPath := func(cntlid uint16, traddr string, current, accessible bool) spdktypes.BdevNvmeIoPath {
+		return spdktypes.BdevNvmeIoPath{
+			BdevName:   "Nvme0n1",
+			Cntlid:     cntlid,
+			Current:    current,
+			Connected:  true,
+			Accessible: accessible,
+			Transport: spdktypes.NvmeTransportID{
+				Trtype:  "TCP",
+				Adrfam:  "IPv4",
+				Traddr:  traddr,
+				Trsvcid: "20001",
+				Subnqn:  "nqn.2023-01.io.longhorn.spdk:vol",
+			},
+		}
+	}
+	server := newFakeServer(map[string]fakeHandler{
+		"bdev_nvme_get_io_paths": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return spdktypes.BdevNvmeIoPaths{
+				PollGroups: []spdktypes.BdevNvmeIoPathPollGroup{{
+					Thread: "app_thread",
+					IoPaths: []spdktypes.BdevNvmeIoPath{
+						newPath(1, "10.0.0.1", true, true),
+						newPath(2, "10.0.0.2", false, true),
+						newPath(3, "10.0.0.3", false, false),
+					},
+				}},
+			}, nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	c.Assert(spdkCli.SwitchBdevNvmeActivePath("Nvme0n1", "10.0.0.2", "20001"), IsNil)
+
+	policyReqs := []spdktypes.BdevNvmeSetMultipathPolicyRequest{}
+	server.requestsOf(c, "bdev_nvme_set_multipath_policy", &policyReqs)
+	c.Assert(policyReqs, DeepEquals, []spdktypes.BdevNvmeSetMultipathPolicyRequest{{
+		Name:   "Nvme0n1",
+		Policy: spdktypes.BdevNvmeMultipathPolicyActivePassive,
+	}})
+	pathReqs := []spdktypes.BdevNvmeSetPreferredPathRequest{}
+	server.requestsOf(c, "bdev_nvme_set_preferred_path", &pathReqs)
+	c.Assert(pathReqs, DeepEquals, []spdktypes.BdevNvmeSetPreferredPathRequest{{Name: "Nvme0n1", Cntlid: 2}})
+
+	// The inaccessible or unknown paths are never preferred
+	c.Assert(spdkCli.SwitchBdevNvmeActivePath("Nvme0n1", "10.0.0.3", "20001"), NotNil)
+	c.Assert(spdkCli.SwitchBdevNvmeActivePath("Nvme0n1", "10.0.0.4", "20001"), NotNil)
+	c.Assert(server.methods(), DeepEquals, []string{
+		"bdev_nvme_get_io_paths",
+		"bdev_nvme_set_multipath_policy",
+		"bdev_nvme_set_preferred_path",
+		"bdev_nvme_get_io_paths",
+		"bdev_nvme_get_io_paths",
+	})
+}
+
+func (s *TestSuite) TestGrowDeviceRetry(c *C) {
+	// The aio bdev was rescanned by a previous call, which failed before growing the lvstore
+	grown := false
+	server := newFakeServer(map[string]fakeHandler{
+		"bdev_get_bdevs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			bdevAio := spdktypes.BdevInfo{
+				BdevInfoBasic: spdktypes.BdevInfoBasic{
+					Name:        "aio",
+					ProductName: spdktypes.BdevProductNameAio,
+					BlockSize:   4096,
+					NumBlocks:   2048,
+				},
+				DriverSpecific: &spdktypes.BdevDriverSpecific{
+					Aio: &spdktypes.BdevDriverSpecificAio{FileName: "/dev/sdb"},
+				},
+			}
+			return []spdktypes.BdevInfo{bdevAio}, nil
+		},
+		"bdev_lvol_get_lvstores": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			lvs := spdktypes.LvstoreInfo{Name: "lvs", TotalDataClusters: 3, FreeClusters: 1}
+			if grown {
+				lvs.TotalDataClusters, lvs.FreeClusters = 7, 5
+			}
+			return []spdktypes.LvstoreInfo{lvs}, nil
+		},
+		"bdev_lvol_grow_lvstore": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			grown = true
+			return true, nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	info, err := spdkCli.GrowDevice("aio", "lvs", time.Hour)
+	c.Assert(err, IsNil)
+	c.Assert(*info, DeepEquals, DeviceGrowInfo{
+		BdevAioName:             "aio",
+		LvsName:                 "lvs",
+		NumBlocksBefore:         2048,
+		NumBlocksAfter:          2048,
+		TotalDataClustersBefore: 3,
+		TotalDataClustersAfter:  7,
+		FreeClustersBefore:      1,
+		FreeClustersAfter:       5,
+	})
+	c.Assert(server.methods(), DeepEquals, []string{
+		"bdev_get_bdevs",
+		"bdev_lvol_get_lvstores",
+		"bdev_aio_rescan",
+		"bdev_get_bdevs",
+		"bdev_lvol_grow_lvstore",
+		"bdev_lvol_get_lvstores",
+	})
+}
+
+func (s *TestSuite) TestStartExposeBdevWithTransportOptions(c *C) {
+	server := newFakeServer(map[string]fakeHandler{
+		"nvmf_get_transports": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.NvmfTransport{}, nil
+		},
+		"nvmf_subsystem_add_ns": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return 1, nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	err := spdkCli.StartExposeBdevWithOptions(StartExposeBdevOptions{
+		Nqn:              types.GetNQN("vol"),
+		BdevName:         "lvs/vol",
+		IP:               types.LocalIP,
+		Port:             "20001",
+		TransportOptions: &NvmfCreateTransportOptions{MaxQueueDepth: 256},
+	})
+	c.Assert(err, IsNil)
+
+	reqs := []spdktypes.NvmfCreateTransportRequest{}
+	server.requestsOf(c, "nvmf_create_transport", &reqs)
+	c.Assert(len(reqs), Equals, 1)
+	c.Assert(reqs[0].Trtype, Equals, spdktypes.NvmeTransportTypeTCP)
+	c.Assert(reqs[0].MaxQueueDepth, Equals, uint32(256))
+	c.Assert(server.methods(), DeepEquals, []string{
+		"nvmf_get_transports",
+		"nvmf_create_transport",
+		"nvmf_create_subsystem",
+		"nvmf_subsystem_add_ns",
+		"nvmf_subsystem_add_listener",
+	})
+}
+
+func newTestRaid(process *spdktypes.BdevRaidProcessInfo, baseBdevs ...spdktypes.BaseBdev) spdktypes.BdevRaidInfo {
+	return spdktypes.BdevRaidInfo{
+		Name:          "raid01",
+		State:         spdktypes.BdevRaidCategoryOnline,
+		RaidLevel:     spdktypes.BdevRaidLevelRaid1,
+		NumBaseBdevs:  uint8(len(baseBdevs)),
+		BaseBdevsList: baseBdevs,
+		Process:       process,
+	}
+}
+
+func newTestRebuildProcess(percent float64) *spdktypes.BdevRaidProcessInfo {
+	return &spdktypes.BdevRaidProcessInfo{
+		Type:     spdktypes.BdevRaidProcessTypeRebuild,
+		Target:   "lvs/lvol2",
+		Progress: spdktypes.BdevRaidProcessProgress{Blocks: uint64(percent * 10), Percent: percent},
+	}
+}
+
+// newRaidServer returns a fake server replying bdev_raid_get_bdevs with the raid bdevs in order, then with the last one forever.
+func newRaidServer(raids ...spdktypes.BdevRaidInfo) *fakeServer {
+	lock := sync.Mutex{}
+	return newFakeServer(map[string]fakeHandler{
+		"bdev_raid_get_bdevs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			lock.Lock()
+			defer lock.Unlock()
+			raid := raids[0]
+			if len(raids) > 1 {
+				raids = raids[1:]
+			}
+			return []spdktypes.BdevRaidInfo{raid}, nil
+		},
+	})
+}
+
+func receiveRaidRebuildProgress(progressCh chan RaidRebuildProgress) []RaidRebuildProgress {
+	close(progressCh)
+	progressList := []RaidRebuildProgress{}
+	for progress := range progressCh {
+		progressList = append(progressList, progress)
+	}
+	return progressList
+}
+
+func (s *TestSuite) TestReplaceBaseBdev(c *C) {
+	lvol0 := spdktypes.BaseBdev{Name: "lvs/lvol0", IsConfigured: true}
+	oldLvol := spdktypes.BaseBdev{Name: "lvs/lvol1", IsConfigured: true}
+	newLvol := spdktypes.BaseBdev{Name: "lvs/lvol2", IsConfigured: true, DataSize: 1000}
+	unconfiguredNewLvol := spdktypes.BaseBdev{Name: "lvs/lvol2"}
+
+	server := newRaidServer(
+		newTestRaid(nil, lvol0, oldLvol),
+		newTestRaid(newTestRebuildProcess(50), lvol0, newLvol),
+		newTestRaid(newTestRebuildProcess(90), lvol0, newLvol),
+		newTestRaid(nil, lvol0, newLvol),
+	)
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	progressCh := make(chan RaidRebuildProgress, 10)
+	err := spdkCli.ReplaceBaseBdev(context.Background(), "raid01", "lvs/lvol1", "lvs/lvol2", time.Millisecond, progressCh)
+	c.Assert(err, IsNil)
+	c.Assert(server.methods(), DeepEquals, []string{
+		"bdev_raid_get_bdevs",
+		"bdev_raid_remove_base_bdev",
+		"bdev_raid_add_base_bdev",
+		"bdev_raid_get_bdevs",
+		"bdev_raid_get_bdevs",
+		"bdev_raid_get_bdevs",
+	})
+	percents := []float64{}
+	for _, progress := range receiveRaidRebuildProgress(progressCh) {
+		c.Assert(progress.TargetBaseBdev, Equals, "lvs/lvol2")
+		percents = append(percents, progress.Percent)
+	}
+	c.Assert(percents, DeepEquals, []float64{50, 90, 100})
+
+	// The base bdev is not rebuilt until it is configured and the raid bdev is online
+	configuringRaid := newTestRaid(nil, lvol0, newLvol)
+	configuringRaid.State = spdktypes.BdevRaidCategoryConfiguring
+	server = newRaidServer(
+		newTestRaid(nil, lvol0, spdktypes.BaseBdev{}),
+		newTestRaid(nil, lvol0, unconfiguredNewLvol),
+		newTestRaid(newTestRebuildProcess(50), lvol0, newLvol),
+		configuringRaid,
+		newTestRaid(nil, lvol0, newLvol),
+	)
+	spdkCli, closeFn = server.newClient()
+	defer closeFn()
+
+	err = spdkCli.ReplaceBaseBdev(context.Background(), "raid01", "", "lvs/lvol2", time.Millisecond, nil)
+	c.Assert(err, IsNil)
+	c.Assert(server.methods(), HasLen, 6)
+
+	server = newRaidServer(
+		newTestRaid(nil, lvol0, spdktypes.BaseBdev{}),
+		newTestRaid(nil, lvol0, newLvol),
+	)
+	spdkCli, closeFn = server.newClient()
+	defer closeFn()
+
+	// The rebuild completes before the first check, and the completion is still reported
+	progressCh = make(chan RaidRebuildProgress, 10)
+	err = spdkCli.ReplaceBaseBdev(context.Background(), "raid01", "", "lvs/lvol2", time.Millisecond, progressCh)
+	c.Assert(err, IsNil)
+	c.Assert(server.methods(), HasLen, 3)
+	progressList := receiveRaidRebuildProgress(progressCh)
+	c.Assert(progressList, HasLen, 1)
+	c.Assert(progressList[0].Percent, Equals, float64(100))
+	c.Assert(progressList[0].Blocks, Equals, uint64(1000))
+
+	// A raid bdev missing from bdev_raid_get_bdevs fails the replacement
+	server = newRaidServer(spdktypes.BdevRaidInfo{Name: "raid02"})
+	spdkCli, closeFn = server.newClient()
+	defer closeFn()
+
+	err = spdkCli.ReplaceBaseBdev(context.Background(), "raid01", "", "lvs/lvol2", time.Millisecond, nil)
+	c.Assert(err, ErrorMatches, "cannot find raid bdev raid01")
+}
+
+func (s *TestSuite) TestReplaceBaseBdevFailure(c *C) {
+	lvol0 := spdktypes.BaseBdev{Name: "lvs/lvol0", IsConfigured: true}
+	newLvol := spdktypes.BaseBdev{Name: "lvs/lvol2", IsConfigured: true}
+
+	// The target is deconfigured once the rebuild fails
+	server := newRaidServer(
+		newTestRaid(nil, lvol0, spdktypes.BaseBdev{}),
+		newTestRaid(newTestRebuildProcess(50), lvol0, newLvol),
+		newTestRaid(nil, lvol0, spdktypes.BaseBdev{Name: "lvs/lvol2"}),
+	)
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	err := spdkCli.ReplaceBaseBdev(context.Background(), "raid01", "", "lvs/lvol2", time.Millisecond, nil)
+	c.Assert(err, ErrorMatches, "rebuild of base bdev lvs/lvol2 in raid bdev raid01 failed")
+
+	// Or it is removed from the raid bdev
+	server = newRaidServer(
+		newTestRaid(nil, lvol0, spdktypes.BaseBdev{}),
+		newTestRaid(newTestRebuildProcess(50), lvol0, newLvol),
+		newTestRaid(nil, lvol0, spdktypes.BaseBdev{}),
+	)
+	spdkCli, closeFn = server.newClient()
+	defer closeFn()
+
+	err = spdkCli.ReplaceBaseBdev(context.Background(), "raid01", "", "lvs/lvol2", time.Millisecond, nil)
+	c.Assert(err, ErrorMatches, "base bdev lvs/lvol2 is gone from raid bdev raid01 during the rebuild")
+
+	offlineRaid := newTestRaid(nil, spdktypes.BaseBdev{}, newLvol)
+	offlineRaid.State = spdktypes.BdevRaidCategoryOffline
+	server = newRaidServer(
+		newTestRaid(nil, lvol0, spdktypes.BaseBdev{}),
+		newTestRaid(newTestRebuildProcess(50), lvol0, newLvol),
+		offlineRaid,
+	)
+	spdkCli, closeFn = server.newClient()
+	defer closeFn()
+
+	err = spdkCli.ReplaceBaseBdev(context.Background(), "raid01", "", "lvs/lvol2", time.Millisecond, nil)
+	c.Assert(err, ErrorMatches, "raid bdev raid01 becomes offline during the rebuild of base bdev lvs/lvol2")
+}
+
+func (s *TestSuite) TestReplaceBaseBdevCanceled(c *C) {
+	lvol0 := spdktypes.BaseBdev{Name: "lvs/lvol0", IsConfigured: true}
+	newLvol := spdktypes.BaseBdev{Name: "lvs/lvol2", IsConfigured: true}
+
+	server := newRaidServer(
+		newTestRaid(nil, lvol0, spdktypes.BaseBdev{}),
+		newTestRaid(newTestRebuildProcess(50), lvol0, newLvol),
+	)
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	progressCh := make(chan RaidRebuildProgress)
+	errCh := make(chan error)
+	go func() {
+		errCh <- spdkCli.ReplaceBaseBdev(ctx, "raid01", "", "lvs/lvol2", time.Millisecond, progressCh)
+	}()
+
+	// The next progress is never received, and the cancellation still stops the wait
+	<-progressCh
+	cancel()
+	select {
+	case err := <-errCh:
+		c.Assert(err, Equals, context.Canceled)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the canceled replacement")
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/longhorn/go-spdk-helper/pkg/types"
+
 	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
 )
 
@@ -16,12 +18,57 @@ type Xattr struct {
 	Value string
 }
 
+// BdevLvolCreateOptions are the options of BdevLvolCreateWithOptions.
+type BdevLvolCreateOptions struct {
+	// LvstoreName is the name of logical volume store to create logical volume on. Either this or LvstoreUUID is required.
+	LvstoreName string
+	// LvstoreUUID is the UUID of logical volume store to create logical volume on. Either this or LvstoreName is required.
+	LvstoreUUID string
+
+	// LvolName is required. The bdev name/alias will be <LVSTORE NAME>/<LVOL NAME>.
+	LvolName string
+	// LvolUUID is optional. A random UUID is generated if this is not specified.
+	// Specify it to recreate a logical volume with its original UUID.
+	LvolUUID string
+
+	// SizeInMib is the logical volume size in Mib. And size will be rounded up to a multiple of cluster size.
+	SizeInMib uint64
+	// ClearMethod is optional. Available: none, unmap, write_zeroes. unmap by default.
+	ClearMethod spdktypes.BdevLvolClearMethod
+	// ThinProvision is false for a thick provisioned logical volume.
+	ThinProvision bool
+
+	// Xattrs is optional. The extended attributes set on creation.
+	Xattrs []Xattr
+}
+
 const (
 	UserCreated       = "user_created"
 	SnapshotTimestamp = "snapshot_timestamp"
 	SnapshotChecksum  = "snapshot_checksum"
 )
 
+// BdevRaidCreateOptions are the options of BdevRaidCreateWithOptions.
+type BdevRaidCreateOptions struct {
+	// Name is required. A RAID bdev name rather than an alias or a UUID.
+	Name string
+	// RaidLevel is required. It can be "0"/"raid0", "1"/"raid1", "5f"/"raid5f", or "concat".
+	RaidLevel spdktypes.BdevRaidLevel
+	// StripSizeKb is the strip size in KB. It's valid for raid0 and raid5f only. For other raid levels, this would be modified to 0.
+	StripSizeKb uint32
+	// BaseBdevs is required. The bdev list used as the underlying disk of the RAID.
+	BaseBdevs []string
+
+	// UUID is optional. A random UUID is generated if this is not specified.
+	UUID string
+	// Superblock stores the RAID configuration on the base bdevs,
+	// so that the RAID bdev is reassembled automatically when the base bdevs show up again, e.g. after spdk_tgt restarts.
+	Superblock bool
+	// DeltaBitmap tracks the regions written while a base bdev is missing,
+	// so that only those regions are resynced when the base bdev comes back.
+	DeltaBitmap bool
+}
+
 // BdevGetBdevs get information about block devices (bdevs).
 //
 //	"name": Optional. If this is not specified, the function will list all block devices.
@@ -71,6 +118,20 @@ func (c *Client) BdevAioDelete(name string) (deleted bool, err error) {
 	return deleted, json.Unmarshal(cmdOutput, &deleted)
 }
 
+// BdevAioRescan rescans the size of the file backing Linux AIO bdev and resizes the bdev accordingly.
+func (c *Client) BdevAioRescan(name string) (rescanned bool, err error) {
+	req := spdktypes.BdevAioRescanRequest{
+		Name: name,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_aio_rescan", req)
+	if err != nil {
+		return false, err
+	}
+
+	return rescanned, json.Unmarshal(cmdOutput, &rescanned)
+}
+
 // BdevAioGet will list all AIO bdevs if a name is not specified.
 //
 //		"name": Optional. Name of an AIO bdev.
@@ -104,11 +165,56 @@ func (c *Client) BdevAioGet(name string, timeout uint64) (bdevAioInfoList []spdk
 }
 
 // BdevLvolCreateLvstore constructs a logical volume store.
+//
+//	"bdevName": Required. The bdev on which to construct the logical volume store.
+//
+//	"lvsName": Required. Name of the logical volume store to create.
+//
+//	"clusterSize": Optional. Cluster size of the logical volume store in bytes. SPDK uses 4MiB if this is 0.
 func (c *Client) BdevLvolCreateLvstore(bdevName, lvsName string, clusterSize uint32) (uuid string, err error) {
+	return c.BdevLvolCreateLvstoreWithOptions(BdevLvolCreateLvstoreOptions{
+		BdevName:    bdevName,
+		LvsName:     lvsName,
+		ClusterSize: clusterSize,
+	})
+}
+
+// BdevLvolCreateLvstoreOptions are the options of BdevLvolCreateLvstoreWithOptions.
+// See BdevLvolCreateLvstore for the meaning of the basic options.
+type BdevLvolCreateLvstoreOptions struct {
+	BdevName    string
+	LvsName     string
+	ClusterSize uint32
+
+	// ClearMethod is optional. How to clear the data of the base bdev on creation: none, unmap or write_zeroes. unmap by default in SPDK.
+	// Use none to skip the unmap that can take minutes on a large freshly provisioned disk.
+	ClearMethod spdktypes.BdevLvolClearMethod
+	// NumMdPagesPerClusterRatio is optional. Reserved metadata pages per cluster. SPDK uses 100 if this is 0.
+	// Increase it for a logical volume store holding many small lvols.
+	NumMdPagesPerClusterRatio uint32
+}
+
+func (opts *BdevLvolCreateLvstoreOptions) validate() error {
+	switch opts.ClearMethod {
+	case "", spdktypes.BdevLvolClearMethodNone, spdktypes.BdevLvolClearMethodUnmap, spdktypes.BdevLvolClearMethodWriteZeroes:
+		return nil
+	}
+	return fmt.Errorf("invalid clear method %s, it should be %s, %s or %s", opts.ClearMethod,
+		spdktypes.BdevLvolClearMethodNone, spdktypes.BdevLvolClearMethodUnmap, spdktypes.BdevLvolClearMethodWriteZeroes)
+}
+
+// BdevLvolCreateLvstoreWithOptions constructs a logical volume store with the options.
+func (c *Client) BdevLvolCreateLvstoreWithOptions(opts BdevLvolCreateLvstoreOptions) (uuid string, err error) {
+	if err := opts.validate(); err != nil {
+		return "", errors.Wrapf(err, "invalid options for creating lvstore %s on bdev %s", opts.LvsName, opts.BdevName)
+	}
+
 	req := spdktypes.BdevLvolCreateLvstoreRequest{
-		BdevName:  bdevName,
-		LvsName:   lvsName,
-		ClusterSz: clusterSize,
+		BdevName:                  opts.BdevName,
+		LvsName:                   opts.LvsName,
+		ClusterSz:                 opts.ClusterSize,
+		ClearMethod:               opts.ClearMethod,
+		NumMdPagesPerClusterRatio: opts.NumMdPagesPerClusterRatio,
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommandWithLongTimeout("bdev_lvol_create_lvstore", req)
@@ -151,9 +257,9 @@ func (c *Client) BdevLvolGetLvstore(lvsName, uuid string) (lvstoreInfoList []spd
 
 // BdevLvolGetLvols receives either lvs_name or UUID.
 func (c *Client) BdevLvolGetLvols(lvsName, uuid string) (lvolInfoList []spdktypes.LvolInfo, err error) {
-	req := spdktypes.BdevLvolGetLvstoreRequest{
+	req := spdktypes.BdevLvolGetLvolsRequest{
 		LvsName: lvsName,
-		UUID:    uuid,
+		LvsUUID: uuid,
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("bdev_lvol_get_lvols", req)
@@ -164,6 +270,45 @@ func (c *Client) BdevLvolGetLvols(lvsName, uuid string) (lvolInfoList []spdktype
 	return lvolInfoList, json.Unmarshal(cmdOutput, &lvolInfoList)
 }
 
+// BdevLvolGetLvolsWithFilter lists the logical volumes of the lvstores that pass the filter.
+//
+//	"lvsName": Optional. Name of the logical volume store. Specify this or "lvsUUID", or neither to list the lvols of all lvstores.
+//
+//	"lvsUUID": Optional. UUID of the logical volume store.
+//
+//	"filter": Only the lvols that pass the filter will be returned.
+func (c *Client) BdevLvolGetLvolsWithFilter(lvsName, lvsUUID string, filter func(*spdktypes.LvolInfo) bool) (lvolInfoList []spdktypes.LvolInfo, err error) {
+	allLvolInfoList, err := c.BdevLvolGetLvols(lvsName, lvsUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	lvolInfoList = []spdktypes.LvolInfo{}
+	for _, lvol := range allLvolInfoList {
+		if !filter(&lvol) {
+			continue
+		}
+		lvolInfoList = append(lvolInfoList, lvol)
+	}
+
+	return lvolInfoList, nil
+}
+
+// BdevLvolGrowLvstore grows a logical volume store to fill the extra space of its base bdev. It receives either lvs_name or UUID.
+func (c *Client) BdevLvolGrowLvstore(lvsName, uuid string) (grown bool, err error) {
+	req := spdktypes.BdevLvolGrowLvstoreRequest{
+		LvsName: lvsName,
+		UUID:    uuid,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommandWithLongTimeout("bdev_lvol_grow_lvstore", req)
+	if err != nil {
+		return false, err
+	}
+
+	return grown, json.Unmarshal(cmdOutput, &grown)
+}
+
 // BdevLvolRenameLvstore renames a logical volume store.
 func (c *Client) BdevLvolRenameLvstore(oldName, newName string) (renamed bool, err error) {
 	req := spdktypes.BdevLvolRenameLvstoreRequest{
@@ -193,16 +338,37 @@ func (c *Client) BdevLvolRenameLvstore(oldName, newName string) (renamed bool, e
 //
 //	"thinProvision": Optional. True to enable thin provisioning. True by default for this API.
 func (c *Client) BdevLvolCreate(lvstoreName, lvstoreUUID, lvolName string, sizeInMib uint64, clearMethod spdktypes.BdevLvolClearMethod, thinProvision bool) (uuid string, err error) {
-	if clearMethod == "" {
-		clearMethod = spdktypes.BdevLvolClearMethodUnmap
-	}
-	req := spdktypes.BdevLvolCreateRequest{
-		LvsName:       lvstoreName,
-		UUID:          lvstoreUUID,
+	return c.BdevLvolCreateWithOptions(BdevLvolCreateOptions{
+		LvstoreName:   lvstoreName,
+		LvstoreUUID:   lvstoreUUID,
 		LvolName:      lvolName,
 		SizeInMib:     sizeInMib,
 		ClearMethod:   clearMethod,
 		ThinProvision: thinProvision,
+	})
+}
+
+// BdevLvolCreateWithOptions create a logical volume on a logical volume store with the full option set.
+// See BdevLvolCreateOptions for the details of each option.
+func (c *Client) BdevLvolCreateWithOptions(opts BdevLvolCreateOptions) (uuid string, err error) {
+	if opts.ClearMethod == "" {
+		opts.ClearMethod = spdktypes.BdevLvolClearMethodUnmap
+	}
+	req := spdktypes.BdevLvolCreateRequest{
+		LvsName:       opts.LvstoreName,
+		UUID:          opts.LvstoreUUID,
+		LvolName:      opts.LvolName,
+		LvolUUID:      opts.LvolUUID,
+		SizeInMib:     opts.SizeInMib,
+		ClearMethod:   opts.ClearMethod,
+		ThinProvision: opts.ThinProvision,
+	}
+
+	if len(opts.Xattrs) > 0 {
+		req.Xattrs = make(map[string]string)
+		for _, s := range opts.Xattrs {
+			req.Xattrs[s.Name] = s.Value
+		}
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("bdev_lvol_create", req)
@@ -320,7 +486,10 @@ func (c *Client) BdevLvolGetWithFilter(name string, timeout uint64, filter func(
 		if !filter(&b) {
 			continue
 		}
-		b.DriverSpecific.Lvol.Xattrs = make(map[string]string)
+		// Keep the xattrs reported by SPDK, if any, and fill in the well-known ones.
+		if b.DriverSpecific.Lvol.Xattrs == nil {
+			b.DriverSpecific.Lvol.Xattrs = make(map[string]string)
+		}
 		user_created, err := c.BdevLvolGetXattr(b.Name, UserCreated)
 		if err == nil {
 			b.DriverSpecific.Lvol.Xattrs[UserCreated] = user_created
@@ -410,6 +579,31 @@ func (c *Client) BdevLvolCloneBdev(bdev, lvsName, cloneName string) (uuid string
 	return uuid, json.Unmarshal(cmdOutput, &uuid)
 }
 
+// BdevLvolCreateEsnapClone creates a logical volume based on an external snapshot bdev identified by its UUID.
+//
+//	"esnapUUID": Required. UUID of the bdev that acts as the external snapshot.
+//
+//	"lvsName": Either this or "lvsUUID" is required. Name of the logical volume store to create the clone on.
+//
+//	"lvsUUID": Either this or "lvsName" is required. UUID of the logical volume store to create the clone on.
+//
+//	"cloneName": Required. Name for the newly created lvol.
+func (c *Client) BdevLvolCreateEsnapClone(esnapUUID, lvsName, lvsUUID, cloneName string) (uuid string, err error) {
+	req := spdktypes.BdevLvolCreateEsnapCloneRequest{
+		EsnapUUID: esnapUUID,
+		LvsName:   lvsName,
+		LvsUUID:   lvsUUID,
+		CloneName: cloneName,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_lvol_create_esnap_clone", req)
+	if err != nil {
+		return "", err
+	}
+
+	return uuid, json.Unmarshal(cmdOutput, &uuid)
+}
+
 // BdevLvolDecoupleParent decouples the parent of a logical volume.
 // For unallocated clusters which is allocated in the parent, they are allocated and copied from the parent,
 // but for unallocated clusters which is thin provisioned in the parent, they are kept thin provisioned. Then all dependencies on the parent are removed.
@@ -486,6 +680,40 @@ func (c *Client) BdevLvolResize(name string, sizeInMib uint64) (resized bool, er
 	return resized, json.Unmarshal(cmdOutput, &resized)
 }
 
+// BdevLvolSetReadOnly marks a logical volume as read only.
+//
+//	"name": Required. UUID or alias of the logical volume to set as read only. The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>.
+func (c *Client) BdevLvolSetReadOnly(name string) (set bool, err error) {
+	req := spdktypes.BdevLvolSetReadOnlyRequest{
+		Name: name,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_lvol_set_read_only", req)
+	if err != nil {
+		return false, err
+	}
+
+	return set, json.Unmarshal(cmdOutput, &set)
+}
+
+// BdevLvolInflate inflates a logical volume.
+// All unallocated clusters are allocated and copied from the parent or zero filled if not allocated in the parent.
+// Then all dependencies on the parent are removed.
+//
+//	"name": Required. UUID or alias of the logical volume to inflate. The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>.
+func (c *Client) BdevLvolInflate(name string) (inflated bool, err error) {
+	req := spdktypes.BdevLvolInflateRequest{
+		Name: name,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommandWithLongTimeout("bdev_lvol_inflate", req)
+	if err != nil {
+		return false, err
+	}
+
+	return inflated, json.Unmarshal(cmdOutput, &inflated)
+}
+
 // BdevLvolStartShallowCopy start a shallow copy of lvol over a given bdev.
 // Only clusters allocated to the lvol will be written on the bdev.
 // Returns the operation ID needed to check the shallow copy status with BdevLvolCheckShallowCopy.
@@ -645,14 +873,28 @@ func (c *Client) BdevLvolRename(oldName, newName string) (renamed bool, err erro
 //
 //	"baseBdevs": Required. The bdev list used as the underlying disk of the RAID.
 func (c *Client) BdevRaidCreate(name string, raidLevel spdktypes.BdevRaidLevel, stripSizeKb uint32, baseBdevs []string) (created bool, err error) {
-	if raidLevel != spdktypes.BdevRaidLevel0 && raidLevel != spdktypes.BdevRaidLevelRaid0 && raidLevel != spdktypes.BdevRaidLevel5f && raidLevel != spdktypes.BdevRaidLevelRaid5f {
-		stripSizeKb = 0
-	}
-	req := spdktypes.BdevRaidCreateRequest{
+	return c.BdevRaidCreateWithOptions(BdevRaidCreateOptions{
 		Name:        name,
 		RaidLevel:   raidLevel,
 		StripSizeKb: stripSizeKb,
 		BaseBdevs:   baseBdevs,
+	})
+}
+
+// BdevRaidCreateWithOptions constructs a new RAID bdev with the full option set.
+// See BdevRaidCreateOptions for the details of each option.
+func (c *Client) BdevRaidCreateWithOptions(opts BdevRaidCreateOptions) (created bool, err error) {
+	if opts.RaidLevel != spdktypes.BdevRaidLevel0 && opts.RaidLevel != spdktypes.BdevRaidLevelRaid0 && opts.RaidLevel != spdktypes.BdevRaidLevel5f && opts.RaidLevel != spdktypes.BdevRaidLevelRaid5f {
+		opts.StripSizeKb = 0
+	}
+	req := spdktypes.BdevRaidCreateRequest{
+		Name:        opts.Name,
+		RaidLevel:   opts.RaidLevel,
+		StripSizeKb: opts.StripSizeKb,
+		BaseBdevs:   opts.BaseBdevs,
+		UUID:        opts.UUID,
+		Superblock:  opts.Superblock,
+		DeltaBitmap: opts.DeltaBitmap,
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("bdev_raid_create", req)
@@ -799,6 +1041,27 @@ func (c *Client) BdevRaidGrowBaseBdev(raidName, baseBdevName string) (growed boo
 	return growed, json.Unmarshal(cmdOutput, &growed)
 }
 
+// BdevRaidAddBaseBdev adds a base bdev to an empty slot of a raid bdev.
+// For a redundant raid bdev, e.g., raid1, a rebuild process targeting the new base bdev starts in the background.
+// The progress is reported in the "process" field of the raid bdev info.
+//
+//	"raidName": Required. The RAID bdev name.
+//
+//	"baseBdevName": Required. The base bdev name to be added to the RAID bdev.
+func (c *Client) BdevRaidAddBaseBdev(raidName, baseBdevName string) (added bool, err error) {
+	req := spdktypes.BdevRaidAddBaseBdevRequest{
+		BaseBdev: baseBdevName,
+		RaidBdev: raidName,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_raid_add_base_bdev", req)
+	if err != nil {
+		return false, err
+	}
+
+	return added, json.Unmarshal(cmdOutput, &added)
+}
+
 // BdevNvmeAttachController constructs NVMe bdev.
 //
 //	"name": Name of the NVMe controller. And the corresponding bdev nvme name are same as the nvme namespace name, which is `{ControllerName}n1`
@@ -811,7 +1074,7 @@ func (c *Client) BdevRaidGrowBaseBdev(raidName, baseBdevName string) (growed boo
 //
 //	"traddr": NVMe-oF target address: ip or BDF
 //
-//	"adrfam": NVMe-oF target adrfam: ipv4, ipv6, ib, fc, intra_host
+//	"adrfam": NVMe-oF target adrfam: ipv4, ipv6, ib, fc, intra_host. Inferred from traddr if empty for "tcp" and "rdma"
 //
 // "ctrlrLossTimeoutSec": Controller loss timeout in seconds
 //
@@ -822,19 +1085,71 @@ func (c *Client) BdevRaidGrowBaseBdev(raidName, baseBdevName string) (growed boo
 // "multipath": Multipathing behavior: disable, failover, multipath. Default is failover
 func (c *Client) BdevNvmeAttachController(name, subnqn, traddr, trsvcid string, trtype spdktypes.NvmeTransportType, adrfam spdktypes.NvmeAddressFamily,
 	ctrlrLossTimeoutSec, reconnectDelaySec, fastIOFailTimeoutSec int32, multipath string) (bdevNameList []string, err error) {
-	req := spdktypes.BdevNvmeAttachControllerRequest{
-		Name: name,
-		NvmeTransportID: spdktypes.NvmeTransportID{
-			Traddr:  traddr,
-			Trtype:  trtype,
-			Subnqn:  subnqn,
-			Trsvcid: trsvcid,
-			Adrfam:  adrfam,
-		},
+	return c.BdevNvmeAttachControllerWithOptions(BdevNvmeAttachControllerOptions{
+		Name:                 name,
+		Subnqn:               subnqn,
+		Traddr:               traddr,
+		Trsvcid:              trsvcid,
+		Trtype:               trtype,
+		Adrfam:               adrfam,
 		CtrlrLossTimeoutSec:  ctrlrLossTimeoutSec,
 		ReconnectDelaySec:    reconnectDelaySec,
 		FastIOFailTimeoutSec: fastIOFailTimeoutSec,
 		Multipath:            multipath,
+	})
+}
+
+// BdevNvmeAttachControllerOptions are the options of BdevNvmeAttachControllerWithOptions.
+// See BdevNvmeAttachController for the meaning of the basic options.
+type BdevNvmeAttachControllerOptions struct {
+	Name    string
+	Subnqn  string
+	Traddr  string
+	Trsvcid string
+	Trtype  spdktypes.NvmeTransportType
+	Adrfam  spdktypes.NvmeAddressFamily
+
+	CtrlrLossTimeoutSec  int32
+	ReconnectDelaySec    int32
+	FastIOFailTimeoutSec int32
+	Multipath            string
+
+	// Hostnqn is optional. The host NQN presented to the target, which must be allowed by the target subsystem if the access control is enabled.
+	Hostnqn string
+	// Psk is optional. Name of the keyring key used as the TLS pre-shared key. It enables TLS for "tcp", and requires Hostnqn to be allowed with the same key by the target.
+	Psk string
+	// DhchapKey is optional. Name of the keyring key used to authenticate the host via DH-HMAC-CHAP.
+	DhchapKey string
+	// DhchapCtrlrKey is optional. Name of the keyring key used to authenticate the controller, i.e., bidirectional authentication.
+	DhchapCtrlrKey string
+}
+
+// BdevNvmeAttachControllerWithOptions constructs NVMe bdev with the full option set.
+func (c *Client) BdevNvmeAttachControllerWithOptions(opts BdevNvmeAttachControllerOptions) (bdevNameList []string, err error) {
+	if opts.Trtype != spdktypes.NvmeTransportTypePCIe {
+		if opts.Adrfam == "" {
+			opts.Adrfam = spdktypes.GetNvmeAddressFamily(opts.Traddr)
+		}
+		opts.Traddr = types.NormalizeIP(opts.Traddr)
+	}
+
+	req := spdktypes.BdevNvmeAttachControllerRequest{
+		Name: opts.Name,
+		NvmeTransportID: spdktypes.NvmeTransportID{
+			Traddr:  opts.Traddr,
+			Trtype:  opts.Trtype,
+			Subnqn:  opts.Subnqn,
+			Trsvcid: opts.Trsvcid,
+			Adrfam:  opts.Adrfam,
+		},
+		CtrlrLossTimeoutSec:  opts.CtrlrLossTimeoutSec,
+		ReconnectDelaySec:    opts.ReconnectDelaySec,
+		FastIOFailTimeoutSec: opts.FastIOFailTimeoutSec,
+		Multipath:            opts.Multipath,
+		Hostnqn:              opts.Hostnqn,
+		Psk:                  opts.Psk,
+		DhchapKey:            opts.DhchapKey,
+		DhchapCtrlrKey:       opts.DhchapCtrlrKey,
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("bdev_nvme_attach_controller", req)
@@ -877,6 +1192,111 @@ func (c *Client) BdevNvmeGetControllers(name string) (controllerInfoList []spdkt
 	return controllerInfoList, json.Unmarshal(cmdOutput, &controllerInfoList)
 }
 
+// BdevNvmeGetControllerHealthInfo gets the SMART / health information of a bdev NVMe controller.
+//
+//	"name": Required. Name of the NVMe controller.
+func (c *Client) BdevNvmeGetControllerHealthInfo(name string) (healthInfo *spdktypes.BdevNvmeControllerHealthInfo, err error) {
+	req := spdktypes.BdevNvmeGetControllerHealthInfoRequest{
+		Name: name,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_nvme_get_controller_health_info", req)
+	if err != nil {
+		return nil, err
+	}
+
+	healthInfo = &spdktypes.BdevNvmeControllerHealthInfo{}
+	if err := json.Unmarshal(cmdOutput, healthInfo); err != nil {
+		return nil, err
+	}
+	return healthInfo, nil
+}
+
+// BdevNvmeGetTransportStatistics gets the transport statistics of all bdev NVMe poll groups.
+func (c *Client) BdevNvmeGetTransportStatistics() (stats *spdktypes.BdevNvmeTransportStatistics, err error) {
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_nvme_get_transport_statistics", struct{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	stats = &spdktypes.BdevNvmeTransportStatistics{}
+	if err := json.Unmarshal(cmdOutput, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// BdevNvmeSetMultipathPolicy sets the multipath policy of a bdev NVMe attached via multiple paths.
+//
+//	"name": Required. Name of the NVMe bdev, e.g., "Nvme0n1".
+//
+//	"policy": Required. "active_passive" sends I/O through the preferred path only, "active_active" spreads I/O over all optimized paths.
+//
+//	"selector": Optional. Path selector of "active_active": "round_robin" or "queue_depth".
+//
+//	"rrMinIo": Optional. Number of I/Os routed to a path before switching to the next one for "round_robin".
+func (c *Client) BdevNvmeSetMultipathPolicy(name string, policy spdktypes.BdevNvmeMultipathPolicy, selector spdktypes.BdevNvmeMultipathSelector, rrMinIo uint32) (set bool, err error) {
+	if selector != "" && policy != spdktypes.BdevNvmeMultipathPolicyActiveActive {
+		return false, fmt.Errorf("selector %s is only supported by policy %s", selector, spdktypes.BdevNvmeMultipathPolicyActiveActive)
+	}
+	if rrMinIo != 0 && selector != spdktypes.BdevNvmeMultipathSelectorRoundRobin {
+		return false, fmt.Errorf("rr_min_io is only supported by selector %s", spdktypes.BdevNvmeMultipathSelectorRoundRobin)
+	}
+
+	req := spdktypes.BdevNvmeSetMultipathPolicyRequest{
+		Name:     name,
+		Policy:   policy,
+		Selector: selector,
+		RrMinIo:  rrMinIo,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_nvme_set_multipath_policy", req)
+	if err != nil {
+		return false, err
+	}
+
+	return set, json.Unmarshal(cmdOutput, &set)
+}
+
+// BdevNvmeSetPreferredPath sets the preferred I/O path of a bdev NVMe with the "active_passive" multipath policy.
+//
+//	"name": Required. Name of the NVMe bdev, e.g., "Nvme0n1".
+//
+//	"cntlid": Required. NVMe-oF controller ID of the preferred path. See BdevNvmeGetIoPaths.
+func (c *Client) BdevNvmeSetPreferredPath(name string, cntlid uint16) (set bool, err error) {
+	req := spdktypes.BdevNvmeSetPreferredPathRequest{
+		Name:   name,
+		Cntlid: cntlid,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_nvme_set_preferred_path", req)
+	if err != nil {
+		return false, err
+	}
+
+	return set, json.Unmarshal(cmdOutput, &set)
+}
+
+// BdevNvmeGetIoPaths gets the I/O paths of bdev NVMe for each poll group.
+//
+//	"name": Optional. Name of the NVMe bdev. If this is not specified, the function will list the paths of all NVMe bdevs.
+func (c *Client) BdevNvmeGetIoPaths(name string) (ioPaths *spdktypes.BdevNvmeIoPaths, err error) {
+	req := spdktypes.BdevNvmeGetIoPathsRequest{
+		Name: name,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("bdev_nvme_get_io_paths", req)
+	if err != nil {
+		return nil, err
+	}
+
+	ioPaths = &spdktypes.BdevNvmeIoPaths{}
+	if err := json.Unmarshal(cmdOutput, ioPaths); err != nil {
+		return nil, err
+	}
+	return ioPaths, nil
+}
+
 // BdevNvmeSetOptions sets global parameters for all bdev NVMe.
 // This RPC may only be called before SPDK subsystems have been initialized or any bdev NVMe
 // has been created.
@@ -946,11 +1366,94 @@ func (c *Client) BdevNvmeGet(name string, timeout uint64) (bdevNvmeInfoList []sp
 //
 //	"trtype": Required. Transport type, "tcp" or "rdma". "tcp" by default.
 func (c *Client) NvmfCreateTransport(trtype spdktypes.NvmeTransportType) (created bool, err error) {
-	if trtype == "" {
-		trtype = spdktypes.NvmeTransportTypeTCP
+	return c.NvmfCreateTransportWithOptions(NvmfCreateTransportOptions{
+		Trtype: trtype,
+	})
+}
+
+const (
+	// nvmfTransportMaxSGLEntries is the max number of io units in an I/O, see NVMF_MAX_SGL_ENTRIES of SPDK.
+	nvmfTransportMaxSGLEntries = 16
+	// nvmfTransportMaxSockPriority is the max socket priority of the TCP transport.
+	nvmfTransportMaxSockPriority = 6
+)
+
+// NvmfCreateTransportOptions are the options of NvmfCreateTransportWithOptions.
+// The zero value of a field means the SPDK default.
+type NvmfCreateTransportOptions struct {
+	// Trtype is the transport type, "tcp" or "rdma". "tcp" by default.
+	Trtype spdktypes.NvmeTransportType
+	// TgtName is optional. Parent NVMe-oF target name.
+	TgtName string
+
+	MaxQueueDepth       uint32
+	MaxIoQpairsPerCtrlr uint32
+	InCapsuleDataSize   uint32
+	MaxIoSize           uint32
+	IoUnitSize          uint32
+	MaxAqDepth          uint32
+	NumSharedBuffers    uint32
+	BufCacheSize        uint32
+	AbortTimeoutSec     uint32
+	DifInsertOrStrip    bool
+	Zcopy               bool
+
+	// C2HSuccess is for the TCP transport only. It disables the C2H success optimization when set to false. true by default.
+	C2HSuccess *bool
+	// SockPriority is for the TCP transport only. It is the socket priority from 0 to 6.
+	SockPriority uint32
+}
+
+func (opts *NvmfCreateTransportOptions) validate() error {
+	if opts.Trtype != spdktypes.NvmeTransportTypeTCP && (opts.C2HSuccess != nil || opts.SockPriority != 0) {
+		return fmt.Errorf("c2h_success and sock_priority are supported by the tcp transport only, but the transport type is %s", opts.Trtype)
+	}
+	if opts.SockPriority > nvmfTransportMaxSockPriority {
+		return fmt.Errorf("invalid sock_priority %d, it should be from 0 to %d", opts.SockPriority, nvmfTransportMaxSockPriority)
+	}
+	if opts.MaxQueueDepth == 1 {
+		return fmt.Errorf("invalid max_queue_depth %d, it should be at least 2", opts.MaxQueueDepth)
+	}
+	if opts.MaxIoSize != 0 && opts.IoUnitSize != 0 {
+		if opts.IoUnitSize > opts.MaxIoSize {
+			return fmt.Errorf("io_unit_size %d is larger than max_io_size %d", opts.IoUnitSize, opts.MaxIoSize)
+		}
+		if opts.MaxIoSize/opts.IoUnitSize > nvmfTransportMaxSGLEntries {
+			return fmt.Errorf("max_io_size %d is more than %d times io_unit_size %d", opts.MaxIoSize, nvmfTransportMaxSGLEntries, opts.IoUnitSize)
+		}
+	}
+	if opts.NumSharedBuffers != 0 && opts.BufCacheSize > opts.NumSharedBuffers {
+		return fmt.Errorf("buf_cache_size %d is larger than num_shared_buffers %d", opts.BufCacheSize, opts.NumSharedBuffers)
+	}
+	return nil
+}
+
+// NvmfCreateTransportWithOptions initializes an NVMe-oF transport with the options.
+// Obviously invalid combinations of the options are rejected before sending the request.
+func (c *Client) NvmfCreateTransportWithOptions(opts NvmfCreateTransportOptions) (created bool, err error) {
+	if opts.Trtype == "" {
+		opts.Trtype = spdktypes.NvmeTransportTypeTCP
+	}
+	if err := opts.validate(); err != nil {
+		return false, errors.Wrapf(err, "invalid options for nvmf transport %s creation", opts.Trtype)
 	}
+
 	req := spdktypes.NvmfCreateTransportRequest{
-		Trtype: trtype,
+		Trtype:              opts.Trtype,
+		TgtName:             opts.TgtName,
+		MaxQueueDepth:       opts.MaxQueueDepth,
+		MaxIoQpairsPerCtrlr: opts.MaxIoQpairsPerCtrlr,
+		InCapsuleDataSize:   opts.InCapsuleDataSize,
+		MaxIoSize:           opts.MaxIoSize,
+		IoUnitSize:          opts.IoUnitSize,
+		MaxAqDepth:          opts.MaxAqDepth,
+		NumSharedBuffers:    opts.NumSharedBuffers,
+		BufCacheSize:        opts.BufCacheSize,
+		AbortTimeoutSec:     opts.AbortTimeoutSec,
+		DifInsertOrStrip:    opts.DifInsertOrStrip,
+		Zcopy:               opts.Zcopy,
+		C2HSuccess:          opts.C2HSuccess,
+		SockPriority:        opts.SockPriority,
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("nvmf_create_transport", req)
@@ -984,9 +1487,51 @@ func (c *Client) NvmfGetTransports(trtype spdktypes.NvmeTransportType, tgtName s
 //
 //	"nqn": Required. Subsystem NQN.
 func (c *Client) NvmfCreateSubsystem(nqn string) (created bool, err error) {
-	req := spdktypes.NvmfCreateSubsystemRequest{
+	return c.NvmfCreateSubsystemWithOptions(NvmfCreateSubsystemOptions{
 		Nqn:          nqn,
 		AllowAnyHost: true,
+	})
+}
+
+// NvmfCreateSubsystemOptions are the options of NvmfCreateSubsystemWithOptions.
+type NvmfCreateSubsystemOptions struct {
+	// Nqn is required. Subsystem NQN.
+	Nqn string
+	// AllowAnyHost allows any host to connect. Otherwise, only the hosts added by NvmfSubsystemAddHost can connect.
+	AllowAnyHost bool
+	// AnaReporting enables the ANA reporting so that the ANA states of the listeners are reported to the hosts.
+	// It is required for steering the multipath hosts by NvmfSubsystemListenerSetAnaState.
+	AnaReporting bool
+
+	// SerialNumber is optional. The host uses it for the /dev/disk/by-id names. "00000000000000000000" by default.
+	SerialNumber string
+	// ModelNumber is optional. "SPDK bdev Controller" by default.
+	ModelNumber string
+	// MaxNamespaces is optional. 0 means unlimited.
+	MaxNamespaces uint32
+	// MinCntlid and MaxCntlid are optional. They limit the controller ID range, 1 to 65519 by default.
+	MinCntlid uint16
+	MaxCntlid uint16
+	// TgtName is optional. Parent NVMe-oF target name.
+	TgtName string
+}
+
+// NvmfCreateSubsystemWithOptions constructs an NVMe over Fabrics target subsystem with the options.
+func (c *Client) NvmfCreateSubsystemWithOptions(opts NvmfCreateSubsystemOptions) (created bool, err error) {
+	if opts.MinCntlid != 0 && opts.MaxCntlid != 0 && opts.MinCntlid > opts.MaxCntlid {
+		return false, fmt.Errorf("invalid cntlid range %d-%d for nvmf subsystem %s creation", opts.MinCntlid, opts.MaxCntlid, opts.Nqn)
+	}
+
+	req := spdktypes.NvmfCreateSubsystemRequest{
+		Nqn:           opts.Nqn,
+		TgtName:       opts.TgtName,
+		SerialNumber:  opts.SerialNumber,
+		ModelNumber:   opts.ModelNumber,
+		AllowAnyHost:  opts.AllowAnyHost,
+		AnaReporting:  opts.AnaReporting,
+		MaxNamespaces: opts.MaxNamespaces,
+		MinCntlid:     opts.MinCntlid,
+		MaxCntlid:     opts.MaxCntlid,
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("nvmf_create_subsystem", req)
@@ -1043,12 +1588,87 @@ func (c *Client) NvmfGetSubsystems(nqn, tgtName string) (subsystemList []spdktyp
 //
 //	"nguid": Optional. Namespace globally unique identifier.
 func (c *Client) NvmfSubsystemAddNs(nqn, bdevName, nguid string) (nsid uint32, err error) {
+	return c.NvmfSubsystemAddNsWithOptions(NvmfSubsystemAddNsOptions{
+		Nqn:      nqn,
+		BdevName: bdevName,
+		Nguid:    nguid,
+	})
+}
+
+// NvmfSubsystemAddNsOptions are the options of NvmfSubsystemAddNsWithOptions.
+type NvmfSubsystemAddNsOptions struct {
+	// Nqn is required. Subsystem NQN.
+	Nqn string
+	// BdevName is required. Name of bdev to expose as a namespace.
+	BdevName string
+
+	// Nsid is optional. The first free namespace ID is assigned by default.
+	Nsid uint32
+	// Nguid is optional. 16-byte namespace globally unique identifier in hexadecimal.
+	Nguid string
+	// Eui64 is optional. 8-byte namespace EUI-64 in hexadecimal.
+	Eui64 string
+	// UUID is optional. The host keeps the same /dev/disk/by-id links across re-exports if it is stable.
+	// The UUID of the bdev is used by default.
+	UUID string
+	// Anagrpid is optional. The ANA group ID, which is the namespace ID by default.
+	Anagrpid uint32
+	// PtplFile is optional. The file persisting the reservations through power loss.
+	PtplFile string
+	// TgtName is optional. Parent NVMe-oF target name.
+	TgtName string
+}
+
+func (opts *NvmfSubsystemAddNsOptions) validate() error {
+	for _, id := range []struct {
+		name   string
+		value  string
+		length int
+	}{
+		{"nguid", opts.Nguid, 32},
+		{"eui64", opts.Eui64, 16},
+		{"uuid", opts.UUID, 32},
+	} {
+		if id.value == "" {
+			continue
+		}
+		if !isHexString(strings.ReplaceAll(id.value, "-", ""), id.length) {
+			return fmt.Errorf("invalid %s %s, it should be %d hexadecimal digits", id.name, id.value, id.length)
+		}
+	}
+	return nil
+}
+
+func isHexString(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// NvmfSubsystemAddNsWithOptions adds a bdev as a namespace of an NVMe over Fabrics target subsystem with the options.
+func (c *Client) NvmfSubsystemAddNsWithOptions(opts NvmfSubsystemAddNsOptions) (nsid uint32, err error) {
+	if err := opts.validate(); err != nil {
+		return 0, errors.Wrapf(err, "invalid options for adding bdev %s as a namespace of subsystem %s", opts.BdevName, opts.Nqn)
+	}
+
 	req := spdktypes.NvmfSubsystemAddNsRequest{
-		Nqn: nqn,
+		Nqn: opts.Nqn,
 		Namespace: spdktypes.NvmfSubsystemNamespace{
-			BdevName: bdevName,
-			Nguid:    nguid,
+			Nsid:     opts.Nsid,
+			BdevName: opts.BdevName,
+			Nguid:    opts.Nguid,
+			Eui64:    opts.Eui64,
+			UUID:     opts.UUID,
+			Anagrpid: opts.Anagrpid,
+			PtplFile: opts.PtplFile,
 		},
+		TgtName: opts.TgtName,
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_add_ns", req)
@@ -1116,6 +1736,23 @@ func (c *Client) NvmfSubsystemsGetNss(nqn, bdevName string, nsid uint32) (nsList
 	return nsList, nil
 }
 
+// newNvmfListenAddress returns the listen address with the defaults filled in. The transport type is "tcp" by default,
+// and the address family is inferred from traddr by default. An IP literal traddr is normalized, e.g., without brackets.
+func newNvmfListenAddress(traddr, trsvcid string, trtype spdktypes.NvmeTransportType, adrfam spdktypes.NvmeAddressFamily) spdktypes.NvmfSubsystemListenAddress {
+	if trtype == "" {
+		trtype = spdktypes.NvmeTransportTypeTCP
+	}
+	if adrfam == "" && trtype != spdktypes.NvmeTransportTypePCIe {
+		adrfam = spdktypes.GetNvmeAddressFamily(traddr)
+	}
+	return spdktypes.NvmfSubsystemListenAddress{
+		Traddr:  types.NormalizeIP(traddr),
+		Trsvcid: trsvcid,
+		Trtype:  trtype,
+		Adrfam:  adrfam,
+	}
+}
+
 // NvmfSubsystemAddListener adds a new listen address to an NVMe-oF subsystem.
 //
 //		"nqn": Required. Subsystem NQN.
@@ -1124,18 +1761,39 @@ func (c *Client) NvmfSubsystemsGetNss(nqn, bdevName string, nsid uint32) (nsList
 //
 //		"trsvcid": Required. NVMe-oF target trsvcid: a port number.
 //
-//		"trtype": Required. NVMe-oF target trtype: "tcp", "rdma" or "pcie". "tcp" by default.
+//		"trtype": Optional. NVMe-oF target trtype: "tcp", "rdma" or "pcie". "tcp" by default.
 //
-//	 	"adrfam": Required. Address family ("ipv4", "ipv6", "ib", or "fc"). "ipv4" by default.
+//	 	"adrfam": Optional. Address family ("ipv4", "ipv6", "ib", or "fc"). Inferred from traddr by default.
 func (c *Client) NvmfSubsystemAddListener(nqn, traddr, trsvcid string, trtype spdktypes.NvmeTransportType, adrfam spdktypes.NvmeAddressFamily) (created bool, err error) {
+	return c.NvmfSubsystemAddListenerWithOptions(NvmfSubsystemAddListenerOptions{
+		Nqn:     nqn,
+		Traddr:  traddr,
+		Trsvcid: trsvcid,
+		Trtype:  trtype,
+		Adrfam:  adrfam,
+	})
+}
+
+// NvmfSubsystemAddListenerOptions are the options of NvmfSubsystemAddListenerWithOptions.
+// See NvmfSubsystemAddListener for the meaning of the basic options.
+type NvmfSubsystemAddListenerOptions struct {
+	Nqn     string
+	Traddr  string
+	Trsvcid string
+	Trtype  spdktypes.NvmeTransportType
+	Adrfam  spdktypes.NvmeAddressFamily
+
+	// SecureChannel is optional. Accept TLS connections only, which is valid for "tcp" only.
+	// The hosts need TLS pre-shared keys, see NvmfSubsystemAddHostWithOptions.
+	SecureChannel bool
+}
+
+// NvmfSubsystemAddListenerWithOptions adds a new listen address to an NVMe-oF subsystem with the options.
+func (c *Client) NvmfSubsystemAddListenerWithOptions(opts NvmfSubsystemAddListenerOptions) (created bool, err error) {
 	req := spdktypes.NvmfSubsystemAddListenerRequest{
-		Nqn: nqn,
-		ListenAddress: spdktypes.NvmfSubsystemListenAddress{
-			Traddr:  traddr,
-			Trsvcid: trsvcid,
-			Trtype:  trtype,
-			Adrfam:  adrfam,
-		},
+		Nqn:           opts.Nqn,
+		ListenAddress: newNvmfListenAddress(opts.Traddr, opts.Trsvcid, opts.Trtype, opts.Adrfam),
+		SecureChannel: opts.SecureChannel,
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_add_listener", req)
@@ -1154,18 +1812,13 @@ func (c *Client) NvmfSubsystemAddListener(nqn, traddr, trsvcid string, trtype sp
 //
 //		"trsvcid": Required. NVMe-oF target trsvcid: a port number.
 //
-//		"trtype": Required. NVMe-oF target trtype: "tcp", "rdma" or "pcie". "tcp" by default.
+//		"trtype": Optional. NVMe-oF target trtype: "tcp", "rdma" or "pcie". "tcp" by default.
 //
-//	 	"adrfam": Required. Address family ("IPv4", "IPv6", "IB", or "FC"). "IPv4" by default.
+//	 	"adrfam": Optional. Address family ("ipv4", "ipv6", "ib", or "fc"). Inferred from traddr by default.
 func (c *Client) NvmfSubsystemRemoveListener(nqn, traddr, trsvcid string, trtype spdktypes.NvmeTransportType, adrfam spdktypes.NvmeAddressFamily) (deleted bool, err error) {
 	req := spdktypes.NvmfSubsystemRemoveListenerRequest{
-		Nqn: nqn,
-		ListenAddress: spdktypes.NvmfSubsystemListenAddress{
-			Traddr:  traddr,
-			Trsvcid: trsvcid,
-			Trtype:  trtype,
-			Adrfam:  adrfam,
-		},
+		Nqn:           nqn,
+		ListenAddress: newNvmfListenAddress(traddr, trsvcid, trtype, adrfam),
 	}
 
 	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_remove_listener", req)
@@ -1199,6 +1852,331 @@ func (c *Client) NvmfSubsystemGetListeners(nqn, tgtName string) (listenerList []
 	return listenerList, json.Unmarshal(cmdOutput, &listenerList)
 }
 
+// NvmfSubsystemListenerSetAnaState sets the ANA state of a listener of an NVMe-oF subsystem.
+// The subsystem should be created with the ANA reporting enabled.
+//
+//	"nqn": Required. Subsystem NQN.
+//
+//	"traddr": Required. NVMe-oF target address of the listener.
+//
+//	"trsvcid": Required. NVMe-oF target trsvcid of the listener.
+//
+//	"trtype": Optional. NVMe-oF target trtype of the listener. "tcp" by default.
+//
+//	"adrfam": Optional. Address family of the listener. Inferred from traddr by default.
+//
+//	"anaState": Required. "optimized", "non_optimized", or "inaccessible".
+//
+//	"anagrpid": Optional. ANA group ID. All ANA groups are set if it is 0.
+func (c *Client) NvmfSubsystemListenerSetAnaState(nqn, traddr, trsvcid string, trtype spdktypes.NvmeTransportType, adrfam spdktypes.NvmeAddressFamily,
+	anaState spdktypes.NvmfSubsystemListenerAnaState, anagrpid uint32) (set bool, err error) {
+	req := spdktypes.NvmfSubsystemListenerSetAnaStateRequest{
+		Nqn:           nqn,
+		ListenAddress: newNvmfListenAddress(traddr, trsvcid, trtype, adrfam),
+		AnaState:      anaState,
+		Anagrpid:      anagrpid,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_listener_set_ana_state", req)
+	if err != nil {
+		return false, err
+	}
+
+	return set, json.Unmarshal(cmdOutput, &set)
+}
+
+// NvmfSubsystemAddHost allows a host to connect to an NVMe-oF subsystem.
+// It takes effect only after "allow any host" is disabled for the subsystem.
+//
+//	"nqn": Required. Subsystem NQN.
+//
+//	"hostNQN": Required. Host NQN to allow.
+//
+//	"dhchapKey": Optional. Name of the keyring key used by the host to authenticate itself via DH-HMAC-CHAP.
+//
+//	"dhchapCtrlrKey": Optional. Name of the keyring key used by the controller to authenticate itself to the host, i.e., bidirectional authentication. It requires "dhchapKey".
+func (c *Client) NvmfSubsystemAddHost(nqn, hostNQN, dhchapKey, dhchapCtrlrKey string) (added bool, err error) {
+	return c.NvmfSubsystemAddHostWithOptions(NvmfSubsystemAddHostOptions{
+		Nqn:            nqn,
+		HostNQN:        hostNQN,
+		DhchapKey:      dhchapKey,
+		DhchapCtrlrKey: dhchapCtrlrKey,
+	})
+}
+
+// NvmfSubsystemAddHostOptions are the options of NvmfSubsystemAddHostWithOptions.
+// See NvmfSubsystemAddHost for the meaning of the basic options.
+type NvmfSubsystemAddHostOptions struct {
+	Nqn            string
+	HostNQN        string
+	DhchapKey      string
+	DhchapCtrlrKey string
+
+	// Psk is optional. Name of the keyring key used as the TLS pre-shared key of the host.
+	// It's required for the connections via secure channel listeners.
+	Psk string
+}
+
+// NvmfSubsystemAddHostWithOptions allows a host to connect to an NVMe-oF subsystem with the options.
+func (c *Client) NvmfSubsystemAddHostWithOptions(opts NvmfSubsystemAddHostOptions) (added bool, err error) {
+	req := spdktypes.NvmfSubsystemAddHostRequest{
+		Nqn:            opts.Nqn,
+		Host:           opts.HostNQN,
+		Psk:            opts.Psk,
+		DhchapKey:      opts.DhchapKey,
+		DhchapCtrlrKey: opts.DhchapCtrlrKey,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_add_host", req)
+	if err != nil {
+		return false, err
+	}
+
+	return added, json.Unmarshal(cmdOutput, &added)
+}
+
+// NvmfSubsystemRemoveHost disallows a host to connect to an NVMe-oF subsystem.
+//
+//	"nqn": Required. Subsystem NQN.
+//
+//	"hostNQN": Required. Host NQN to remove.
+func (c *Client) NvmfSubsystemRemoveHost(nqn, hostNQN string) (removed bool, err error) {
+	req := spdktypes.NvmfSubsystemRemoveHostRequest{
+		Nqn:  nqn,
+		Host: hostNQN,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_remove_host", req)
+	if err != nil {
+		return false, err
+	}
+
+	return removed, json.Unmarshal(cmdOutput, &removed)
+}
+
+// NvmfSubsystemAllowAnyHost enables or disables the access control of an NVMe-oF subsystem.
+//
+//	"nqn": Required. Subsystem NQN.
+//
+//	"allowAnyHost": Required. If false, only the hosts added by NvmfSubsystemAddHost can connect to the subsystem.
+func (c *Client) NvmfSubsystemAllowAnyHost(nqn string, allowAnyHost bool) (set bool, err error) {
+	req := spdktypes.NvmfSubsystemAllowAnyHostRequest{
+		Nqn:          nqn,
+		AllowAnyHost: allowAnyHost,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_allow_any_host", req)
+	if err != nil {
+		return false, err
+	}
+
+	return set, json.Unmarshal(cmdOutput, &set)
+}
+
+// NvmfDiscoveryAddReferral adds a referral to the discovery service, so that the discovery log page of this target
+// also lists another discovery service or subsystem.
+//
+//	"traddr": Required. NVMe-oF target address of the referral.
+//
+//	"trsvcid": Required. NVMe-oF target trsvcid of the referral: a port number.
+//
+//	"trtype": Optional. NVMe-oF target trtype: "tcp", "rdma" or "pcie". "tcp" by default.
+//
+//	"adrfam": Optional. Address family ("ipv4", "ipv6", "ib", or "fc"). Inferred from traddr by default.
+//
+//	"subnqn": Optional. NQN of the subsystem referred to. The referral is to a discovery service if not specified.
+//
+//	"secureChannel": Optional. The referral requires TLS connections.
+func (c *Client) NvmfDiscoveryAddReferral(traddr, trsvcid string, trtype spdktypes.NvmeTransportType, adrfam spdktypes.NvmeAddressFamily, subnqn string, secureChannel bool) (added bool, err error) {
+	req := spdktypes.NvmfDiscoveryAddReferralRequest{
+		Address:       newNvmfListenAddress(traddr, trsvcid, trtype, adrfam),
+		Subnqn:        subnqn,
+		SecureChannel: secureChannel,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_discovery_add_referral", req)
+	if err != nil {
+		return false, err
+	}
+
+	return added, json.Unmarshal(cmdOutput, &added)
+}
+
+// NvmfDiscoveryRemoveReferral removes a referral from the discovery service.
+//
+//	"traddr": Required. NVMe-oF target address of the referral.
+//
+//	"trsvcid": Required. NVMe-oF target trsvcid of the referral: a port number.
+//
+//	"trtype": Optional. NVMe-oF target trtype: "tcp", "rdma" or "pcie". "tcp" by default.
+//
+//	"adrfam": Optional. Address family ("ipv4", "ipv6", "ib", or "fc"). Inferred from traddr by default.
+//
+//	"subnqn": Optional. NQN of the subsystem referred to.
+func (c *Client) NvmfDiscoveryRemoveReferral(traddr, trsvcid string, trtype spdktypes.NvmeTransportType, adrfam spdktypes.NvmeAddressFamily, subnqn string) (removed bool, err error) {
+	req := spdktypes.NvmfDiscoveryRemoveReferralRequest{
+		Address: newNvmfListenAddress(traddr, trsvcid, trtype, adrfam),
+		Subnqn:  subnqn,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_discovery_remove_referral", req)
+	if err != nil {
+		return false, err
+	}
+
+	return removed, json.Unmarshal(cmdOutput, &removed)
+}
+
+// NvmfDiscoveryGetReferrals lists the referrals of the discovery service.
+func (c *Client) NvmfDiscoveryGetReferrals() (referralList []spdktypes.NvmfDiscoveryReferral, err error) {
+	req := spdktypes.NvmfDiscoveryGetReferralsRequest{}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_discovery_get_referrals", req)
+	if err != nil {
+		return nil, err
+	}
+
+	return referralList, json.Unmarshal(cmdOutput, &referralList)
+}
+
+// NvmfSubsystemPause pauses the I/O of an NVMe-oF subsystem. The I/O in flight is completed before it returns,
+// and the new I/O is queued until NvmfSubsystemResume.
+//
+//	"nqn": Required. Subsystem NQN.
+//
+//	"nsid": Optional. Only the namespace with the ID is paused if specified. 0 means all namespaces.
+func (c *Client) NvmfSubsystemPause(nqn string, nsid uint32) (paused bool, err error) {
+	req := spdktypes.NvmfSubsystemPauseRequest{
+		Nqn:  nqn,
+		Nsid: nsid,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_pause", req)
+	if err != nil {
+		return false, err
+	}
+
+	return paused, json.Unmarshal(cmdOutput, &paused)
+}
+
+// NvmfSubsystemResume resumes the I/O of an NVMe-oF subsystem paused by NvmfSubsystemPause.
+//
+//	"nqn": Required. Subsystem NQN.
+func (c *Client) NvmfSubsystemResume(nqn string) (resumed bool, err error) {
+	req := spdktypes.NvmfSubsystemResumeRequest{
+		Nqn: nqn,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_resume", req)
+	if err != nil {
+		return false, err
+	}
+
+	return resumed, json.Unmarshal(cmdOutput, &resumed)
+}
+
+// NvmfSubsystemGetControllers lists the controllers of an NVMe-oF subsystem, i.e., the connected hosts.
+//
+//	"nqn": Required. Subsystem NQN.
+//
+//	"tgtName": Optional. Parent NVMe-oF target name.
+func (c *Client) NvmfSubsystemGetControllers(nqn, tgtName string) (controllerList []spdktypes.NvmfSubsystemController, err error) {
+	req := spdktypes.NvmfSubsystemGetControllersRequest{
+		Nqn:     nqn,
+		TgtName: tgtName,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_get_controllers", req)
+	if err != nil {
+		return nil, err
+	}
+
+	return controllerList, json.Unmarshal(cmdOutput, &controllerList)
+}
+
+// NvmfSubsystemGetQpairs lists the queue pairs of an NVMe-oF subsystem.
+//
+//	"nqn": Required. Subsystem NQN.
+//
+//	"tgtName": Optional. Parent NVMe-oF target name.
+func (c *Client) NvmfSubsystemGetQpairs(nqn, tgtName string) (qpairList []spdktypes.NvmfSubsystemQpair, err error) {
+	req := spdktypes.NvmfSubsystemGetQpairsRequest{
+		Nqn:     nqn,
+		TgtName: tgtName,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_subsystem_get_qpairs", req)
+	if err != nil {
+		return nil, err
+	}
+
+	return qpairList, json.Unmarshal(cmdOutput, &qpairList)
+}
+
+// NvmfGetStats gets the statistics of the poll groups of an NVMe-oF target.
+//
+//	"tgtName": Optional. Parent NVMe-oF target name.
+func (c *Client) NvmfGetStats(tgtName string) (stats *spdktypes.NvmfStats, err error) {
+	req := spdktypes.NvmfGetStatsRequest{
+		TgtName: tgtName,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("nvmf_get_stats", req)
+	if err != nil {
+		return nil, err
+	}
+
+	stats = &spdktypes.NvmfStats{}
+	return stats, json.Unmarshal(cmdOutput, stats)
+}
+
+// KeyringFileAddKey adds a key stored in a file to the keyring.
+//
+//	"name": Required. Name of the key. It is the name referred by other RPCs, e.g., NvmfSubsystemAddHost.
+//
+//	"path": Required. Absolute path of the key file. The file must be accessible by the owner only, e.g., with mode 0600.
+func (c *Client) KeyringFileAddKey(name, path string) (added bool, err error) {
+	req := spdktypes.KeyringFileAddKeyRequest{
+		Name: name,
+		Path: path,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("keyring_file_add_key", req)
+	if err != nil {
+		return false, err
+	}
+
+	return added, json.Unmarshal(cmdOutput, &added)
+}
+
+// KeyringFileRemoveKey removes a key added by KeyringFileAddKey. The key file is left untouched.
+//
+//	"name": Required. Name of the key.
+func (c *Client) KeyringFileRemoveKey(name string) (removed bool, err error) {
+	req := spdktypes.KeyringFileRemoveKeyRequest{
+		Name: name,
+	}
+
+	cmdOutput, err := c.jsonCli.SendCommand("keyring_file_remove_key", req)
+	if err != nil {
+		return false, err
+	}
+
+	return removed, json.Unmarshal(cmdOutput, &removed)
+}
+
+// KeyringGetKeys lists all keys in the keyring. The key material is never reported.
+func (c *Client) KeyringGetKeys() (keyList []spdktypes.KeyringKey, err error) {
+	req := spdktypes.KeyringGetKeysRequest{}
+
+	cmdOutput, err := c.jsonCli.SendCommand("keyring_get_keys", req)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyList, json.Unmarshal(cmdOutput, &keyList)
+}
+
 // LogSetFlag sets the log flag.
 //
 // "flag": Required. Log flag to set.
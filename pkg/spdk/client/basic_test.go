@@ -0,0 +1,171 @@
+package client
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+func (s *TestSuite) TestNvmfCreateTransportWithOptions(c *C) {
+	server := newFakeServer(nil)
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	c2hSuccess := false
+	created, err := spdkCli.NvmfCreateTransportWithOptions(NvmfCreateTransportOptions{
+		MaxQueueDepth: 256,
+		MaxIoSize:     131072,
+		IoUnitSize:    16384,
+		Zcopy:         true,
+		C2HSuccess:    &c2hSuccess,
+		SockPriority:  3,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(created, Equals, true)
+
+	reqs := []map[string]interface{}{}
+	server.requestsOf(c, "nvmf_create_transport", &reqs)
+	c.Assert(reqs, DeepEquals, []map[string]interface{}{{
+		"trtype":          "tcp",
+		"max_queue_depth": float64(256),
+		"max_io_size":     float64(131072),
+		"io_unit_size":    float64(16384),
+		"zcopy":           true,
+		"c2h_success":     false,
+		"sock_priority":   float64(3),
+	}})
+
+	invalidOptions := []NvmfCreateTransportOptions{
+		{Trtype: spdktypes.NvmeTransportTypeRDMA, SockPriority: 1},
+		{Trtype: spdktypes.NvmeTransportTypeRDMA, C2HSuccess: &c2hSuccess},
+		{SockPriority: 7},
+		{MaxQueueDepth: 1},
+		{MaxIoSize: 4096, IoUnitSize: 8192},
+		{MaxIoSize: 131072, IoUnitSize: 4096},
+		{NumSharedBuffers: 511, BufCacheSize: 512},
+	}
+	for idx, opts := range invalidOptions {
+		_, err := spdkCli.NvmfCreateTransportWithOptions(opts)
+		c.Assert(err, NotNil, Commentf("test case %d", idx))
+	}
+	c.Assert(server.methods(), DeepEquals, []string{"nvmf_create_transport"})
+}
+
+func (s *TestSuite) TestNvmfSubsystemAddNsWithOptions(c *C) {
+	server := newFakeServer(map[string]fakeHandler{
+		"nvmf_subsystem_add_ns": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return 3, nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	opts := NvmfSubsystemAddNsOptions{
+		Nqn:      "nqn.2023-01.io.longhorn.spdk:vol",
+		BdevName: "lvs/vol",
+		Nsid:     3,
+		Eui64:    "0123456789ABCDEF",
+		UUID:     "8b5c1a1e-5f5c-4d8e-9b4b-6a1b2c3d4e5f",
+		Anagrpid: 2,
+		PtplFile: "/var/lib/spdk/vol.ptpl",
+	}
+	nsid, err := spdkCli.NvmfSubsystemAddNsWithOptions(opts)
+	c.Assert(err, IsNil)
+	c.Assert(nsid, Equals, uint32(3))
+
+	reqs := []spdktypes.NvmfSubsystemAddNsRequest{}
+	server.requestsOf(c, "nvmf_subsystem_add_ns", &reqs)
+	c.Assert(reqs[0].Namespace, DeepEquals, spdktypes.NvmfSubsystemNamespace{
+		Nsid:     3,
+		BdevName: "lvs/vol",
+		Eui64:    "0123456789ABCDEF",
+		UUID:     "8b5c1a1e-5f5c-4d8e-9b4b-6a1b2c3d4e5f",
+		Anagrpid: 2,
+		PtplFile: "/var/lib/spdk/vol.ptpl",
+	})
+
+	for _, invalidOpts := range []NvmfSubsystemAddNsOptions{
+		{Nqn: opts.Nqn, BdevName: opts.BdevName, Nguid: "0123"},
+		{Nqn: opts.Nqn, BdevName: opts.BdevName, Eui64: "0123456789ABCDEG"},
+		{Nqn: opts.Nqn, BdevName: opts.BdevName, UUID: "not-a-uuid"},
+	} {
+		_, err := spdkCli.NvmfSubsystemAddNsWithOptions(invalidOpts)
+		c.Assert(err, NotNil)
+	}
+	c.Assert(server.methods(), DeepEquals, []string{"nvmf_subsystem_add_ns"})
+}
+
+func (s *TestSuite) TestBdevNvmeGetControllerHealthInfo(c *C) {
+	server := newFakeServer(map[string]fakeHandler{
+		"bdev_nvme_get_controller_health_info": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return json.RawMessage(`{
+				"model_number": "SPDK bdev Controller",
+				"traddr": "0000:00:04.0",
+				"temperature_celsius": 38,
+				"percentage_used": 2,
+				"data_units_read": 18446744073709551617,
+				"data_units_written": 340282366920938463463374607431768211455,
+				"media_errors": 0,
+				"warning_temperature_time_minutes": 9007199254740993
+			}`), nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	healthInfo, err := spdkCli.BdevNvmeGetControllerHealthInfo("nvme0")
+	c.Assert(err, IsNil)
+	c.Assert(healthInfo.Traddr, Equals, "0000:00:04.0")
+	c.Assert(healthInfo.TemperatureCelsius, Equals, uint64(38))
+	c.Assert(healthInfo.PercentageUsed, Equals, uint64(2))
+	c.Assert(healthInfo.DataUnitsRead, Equals, spdktypes.Uint128{Hi: 1, Lo: 1})
+	c.Assert(healthInfo.DataUnitsRead.String(), Equals, "18446744073709551617")
+	c.Assert(healthInfo.DataUnitsWritten.String(), Equals, "340282366920938463463374607431768211455")
+	c.Assert(healthInfo.MediaErrors, Equals, spdktypes.Uint128{})
+	c.Assert(healthInfo.WarningTemperatureTimeMinutes, Equals, uint64(9007199254740993))
+
+	reqs := []spdktypes.BdevNvmeGetControllerHealthInfoRequest{}
+	server.requestsOf(c, "bdev_nvme_get_controller_health_info", &reqs)
+	c.Assert(reqs, DeepEquals, []spdktypes.BdevNvmeGetControllerHealthInfoRequest{{Name: "nvme0"}})
+}
+
+func (s *TestSuite) TestBdevLvolCreateLvstoreWithOptions(c *C) {
+	server := newFakeServer(map[string]fakeHandler{
+		"bdev_lvol_create_lvstore": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return "4b0f1a3e-9e56-4b8d-8c2a-1f3d5e7a9b0c", nil
+		},
+	})
+	spdkCli, closeFn := server.newClient()
+	defer closeFn()
+
+	uuid, err := spdkCli.BdevLvolCreateLvstoreWithOptions(BdevLvolCreateLvstoreOptions{
+		BdevName:                  "aio",
+		LvsName:                   "lvs",
+		ClusterSize:               1048576,
+		ClearMethod:               spdktypes.BdevLvolClearMethodNone,
+		NumMdPagesPerClusterRatio: 200,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(uuid, Equals, "4b0f1a3e-9e56-4b8d-8c2a-1f3d5e7a9b0c")
+
+	reqs := []spdktypes.BdevLvolCreateLvstoreRequest{}
+	server.requestsOf(c, "bdev_lvol_create_lvstore", &reqs)
+	c.Assert(reqs, DeepEquals, []spdktypes.BdevLvolCreateLvstoreRequest{{
+		BdevName:                  "aio",
+		LvsName:                   "lvs",
+		ClusterSz:                 1048576,
+		ClearMethod:               spdktypes.BdevLvolClearMethodNone,
+		NumMdPagesPerClusterRatio: 200,
+	}})
+
+	// An unknown clear method is rejected before anything is created
+	_, err = spdkCli.BdevLvolCreateLvstoreWithOptions(BdevLvolCreateLvstoreOptions{BdevName: "aio", LvsName: "lvs", ClearMethod: "zero"})
+	c.Assert(err, NotNil)
+	_, _, _, err = spdkCli.AddDeviceWithOptions(AddDeviceOptions{DevicePath: "/dev/sdb", ClearMethod: "zero"})
+	c.Assert(err, NotNil)
+	c.Assert(server.methods(), DeepEquals, []string{"bdev_lvol_create_lvstore"})
+}
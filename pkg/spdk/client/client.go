@@ -29,6 +29,14 @@ func NewClient(ctx context.Context) (*Client, error) {
 	}, nil
 }
 
+// NewClientWithConn creates a client talking with the SPDK JSON-RPC server over the established connection.
+func NewClientWithConn(ctx context.Context, conn net.Conn) *Client {
+	return &Client{
+		conn:    conn,
+		jsonCli: jsonrpc.NewClient(ctx, conn),
+	}
+}
+
 func (c *Client) Close() error {
 	if c.conn == nil {
 		return nil
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc/jsonrpctest"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+type fakeHandler = jsonrpctest.Handler
+
+// fakeServer stands in for spdk_tgt, see jsonrpctest.Server.
+type fakeServer struct {
+	*jsonrpctest.Server
+}
+
+func newFakeServer(handlers map[string]fakeHandler) *fakeServer {
+	return &fakeServer{
+		Server: jsonrpctest.NewServer(handlers),
+	}
+}
+
+// newClient returns a client talking with the server, and the function to close the connection.
+func (s *fakeServer) newClient() (*Client, func()) {
+	conn, closeConn := s.Dial()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return NewClientWithConn(ctx, conn), func() {
+		cancel()
+		closeConn()
+	}
+}
+
+// methods returns the methods of the received requests in order.
+func (s *fakeServer) methods() []string {
+	return s.Methods()
+}
+
+// requestsOf returns the params of the received requests with the method, decoded as the type of the element of out.
+func (s *fakeServer) requestsOf(c *C, method string, out interface{}) {
+	c.Assert(s.RequestsOf(method, out), IsNil)
+}
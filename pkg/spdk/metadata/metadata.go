@@ -0,0 +1,202 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+)
+
+const KeySeparator = "."
+
+// ConflictError is returned by CompareAndSet when the stored value is not the expected one.
+type ConflictError struct {
+	Lvol     string
+	Key      string
+	Expected string
+	Actual   string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("xattr %s of lvol %s is %q rather than the expected %q", e.Key, e.Lvol, e.Actual, e.Expected)
+}
+
+func IsConflictError(err error) bool {
+	var conflictErr *ConflictError
+	return errors.As(err, &conflictErr)
+}
+
+// Store keeps JSON-encoded values in lvol xattrs, with all keys prefixed by a namespace.
+// For example, the key "spec" in the namespace "longhorn.replica" is stored in the xattr "longhorn.replica.spec".
+type Store struct {
+	sync.Mutex
+
+	spdkCli   *client.Client
+	namespace string
+}
+
+// NewStore creates a metadata store.
+//
+//	"namespace": Required. The prefix of all xattrs managed by this store, e.g. "longhorn.volume".
+func NewStore(spdkCli *client.Client, namespace string) (*Store, error) {
+	if spdkCli == nil {
+		return nil, fmt.Errorf("empty SPDK client for metadata store creation")
+	}
+	namespace = strings.Trim(namespace, KeySeparator)
+	if namespace == "" {
+		return nil, fmt.Errorf("empty namespace for metadata store creation")
+	}
+
+	return &Store{
+		spdkCli:   spdkCli,
+		namespace: namespace,
+	}, nil
+}
+
+// XattrName returns the name of the xattr storing the key.
+func (s *Store) XattrName(key string) string {
+	return s.namespace + KeySeparator + key
+}
+
+// Get decodes the value of the key into v.
+// It returns false without touching v if the key is not set or is deleted.
+//
+//	"lvol": Required. UUID or alias of the logical volume. The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>.
+func (s *Store) Get(lvol, key string, v interface{}) (found bool, err error) {
+	raw, found, err := s.getRaw(lvol, key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return false, errors.Wrapf(err, "failed to decode xattr %s of lvol %s", s.XattrName(key), lvol)
+	}
+	return true, nil
+}
+
+// GetAll returns the raw JSON values of all keys in the namespace, keyed without the namespace prefix.
+// The values are read in bulk from the xattrs reported by bdev_get_bdevs.
+//
+//	"lvol": Required. UUID or alias of the logical volume. The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>.
+func (s *Store) GetAll(lvol string) (map[string]json.RawMessage, error) {
+	bdevLvol, err := s.spdkCli.BdevLvolGetByName(lvol, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get lvol %s", lvol)
+	}
+
+	prefix := s.namespace + KeySeparator
+	values := map[string]json.RawMessage{}
+	for name, raw := range bdevLvol.DriverSpecific.Lvol.Xattrs {
+		key := strings.TrimPrefix(name, prefix)
+		if key == name || key == "" || raw == "" {
+			continue
+		}
+		values[key] = json.RawMessage(raw)
+	}
+	return values, nil
+}
+
+// Set encodes v and stores it as the value of the key.
+//
+//	"lvol": Required. UUID or alias of the logical volume. The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>.
+func (s *Store) Set(lvol, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode xattr %s of lvol %s", s.XattrName(key), lvol)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	return s.setRaw(lvol, key, string(raw))
+}
+
+// Delete clears the value of the key.
+// SPDK provides no RPC to remove a xattr, so the xattr is kept with an empty value, which Get and GetAll treat as not set.
+//
+//	"lvol": Required. UUID or alias of the logical volume. The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>.
+func (s *Store) Delete(lvol, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.setRaw(lvol, key, "")
+}
+
+// CompareAndSet stores v as the value of the key only if the current value equals expected.
+// A nil expected means the key must not be set yet. A ConflictError is returned if the check fails.
+//
+// The check and the write are serialized among the callers of this store only,
+// since SPDK provides no atomic compare-and-set for xattrs.
+//
+//	"lvol": Required. UUID or alias of the logical volume. The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>.
+func (s *Store) CompareAndSet(lvol, key string, expected, v interface{}) error {
+	expectedRaw := ""
+	if expected != nil {
+		encoded, err := json.Marshal(expected)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encode the expected value of xattr %s of lvol %s", s.XattrName(key), lvol)
+		}
+		expectedRaw = string(encoded)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode xattr %s of lvol %s", s.XattrName(key), lvol)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	actualRaw, _, err := s.getRaw(lvol, key)
+	if err != nil {
+		return err
+	}
+	if actualRaw != expectedRaw {
+		return &ConflictError{
+			Lvol:     lvol,
+			Key:      s.XattrName(key),
+			Expected: expectedRaw,
+			Actual:   actualRaw,
+		}
+	}
+
+	return s.setRaw(lvol, key, string(raw))
+}
+
+// Xattrs encodes the values into the xattr list accepted by BdevLvolSnapshot.
+func (s *Store) Xattrs(values map[string]interface{}) ([]client.Xattr, error) {
+	xattrs := make([]client.Xattr, 0, len(values))
+	for key, v := range values {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode xattr %s", s.XattrName(key))
+		}
+		xattrs = append(xattrs, client.Xattr{
+			Name:  s.XattrName(key),
+			Value: string(raw),
+		})
+	}
+	return xattrs, nil
+}
+
+func (s *Store) getRaw(lvol, key string) (raw string, found bool, err error) {
+	raw, err = s.spdkCli.BdevLvolGetXattr(lvol, s.XattrName(key))
+	if err != nil {
+		if jsonrpc.IsJSONRPCRespErrorNoSuchFile(err) {
+			return "", false, nil
+		}
+		return "", false, errors.Wrapf(err, "failed to get xattr %s of lvol %s", s.XattrName(key), lvol)
+	}
+	return raw, raw != "", nil
+}
+
+func (s *Store) setRaw(lvol, key, raw string) error {
+	if _, err := s.spdkCli.BdevLvolSetXattr(lvol, s.XattrName(key), raw); err != nil {
+		return errors.Wrapf(err, "failed to set xattr %s of lvol %s", s.XattrName(key), lvol)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc/jsonrpctest"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+// newXattrServer returns a fake server keeping the xattrs of the lvols in memory, keyed by the lvol name then the xattr name.
+// Like SPDK, it reports the xattrs of a lvol in the driver specific info of bdev_get_bdevs.
+func newXattrServer(xattrs map[string]map[string]string) *jsonrpctest.Server {
+	lock := sync.Mutex{}
+	return jsonrpctest.NewServer(map[string]jsonrpctest.Handler{
+		"bdev_get_bdevs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevGetBdevsRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			lvolXattrs, ok := xattrs[req.Name]
+			if !ok {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchDevice, Message: "No such device"}
+			}
+			reported := map[string]string{}
+			for name, value := range lvolXattrs {
+				reported[name] = value
+			}
+			return []spdktypes.BdevInfo{{
+				BdevInfoBasic: spdktypes.BdevInfoBasic{
+					Name:        req.Name,
+					ProductName: spdktypes.BdevProductNameLvol,
+				},
+				DriverSpecific: &spdktypes.BdevDriverSpecific{
+					Lvol: &spdktypes.BdevDriverSpecificLvol{
+						Xattrs: reported,
+					},
+				},
+			}}, nil
+		},
+		"bdev_lvol_get_xattr": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolGetXattrRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			value, ok := xattrs[req.Name][req.XattrName]
+			if !ok {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchFile, Message: "No such file or directory"}
+			}
+			return value, nil
+		},
+		"bdev_lvol_set_xattr": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolSetXattrRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if xattrs[req.Name] == nil {
+				xattrs[req.Name] = map[string]string{}
+			}
+			xattrs[req.Name][req.XattrName] = req.XattrValue
+			return true, nil
+		},
+	})
+}
+
+func newTestStore(c *C, server *jsonrpctest.Server, namespace string) (*Store, func()) {
+	conn, closeConn := server.Dial()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	store, err := NewStore(client.NewClientWithConn(ctx, conn), namespace)
+	c.Assert(err, IsNil)
+	return store, func() {
+		cancel()
+		closeConn()
+	}
+}
+
+type testSpec struct {
+	Size     uint64 `json:"size"`
+	Frontend string `json:"frontend"`
+}
+
+func (s *TestSuite) TestStoreGetSet(c *C) {
+	xattrs := map[string]map[string]string{}
+	server := newXattrServer(xattrs)
+	store, closeFn := newTestStore(c, server, ".longhorn.replica.")
+	defer closeFn()
+
+	spec := testSpec{}
+	found, err := store.Get("lvs/vol", "spec", &spec)
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, false)
+
+	err = store.Set("lvs/vol", "spec", testSpec{Size: 1024, Frontend: "spdk-tcp-blockdev"})
+	c.Assert(err, IsNil)
+	c.Assert(xattrs["lvs/vol"]["longhorn.replica.spec"], Equals, `{"size":1024,"frontend":"spdk-tcp-blockdev"}`)
+
+	found, err = store.Get("lvs/vol", "spec", &spec)
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, true)
+	c.Assert(spec, Equals, testSpec{Size: 1024, Frontend: "spdk-tcp-blockdev"})
+
+	// Decoding into a mismatched type fails rather than being silently ignored
+	var size uint64
+	_, err = store.Get("lvs/vol", "spec", &size)
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite) TestStoreCompareAndSet(c *C) {
+	xattrs := map[string]map[string]string{}
+	server := newXattrServer(xattrs)
+	store, closeFn := newTestStore(c, server, "longhorn.replica")
+	defer closeFn()
+
+	// A nil expected value means the key must not be set yet
+	err := store.CompareAndSet("lvs/vol", "generation", nil, 1)
+	c.Assert(err, IsNil)
+	err = store.CompareAndSet("lvs/vol", "generation", nil, 1)
+	c.Assert(IsConflictError(err), Equals, true)
+
+	err = store.CompareAndSet("lvs/vol", "generation", 1, 2)
+	c.Assert(err, IsNil)
+
+	err = store.CompareAndSet("lvs/vol", "generation", 1, 3)
+	c.Assert(IsConflictError(err), Equals, true)
+	conflictErr := err.(*ConflictError)
+	c.Assert(conflictErr.Key, Equals, "longhorn.replica.generation")
+	c.Assert(conflictErr.Expected, Equals, "1")
+	c.Assert(conflictErr.Actual, Equals, "2")
+
+	// The conflicting write leaves the value as it is
+	generation := 0
+	found, err := store.Get("lvs/vol", "generation", &generation)
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, true)
+	c.Assert(generation, Equals, 2)
+}
+
+func (s *TestSuite) TestStoreGetAll(c *C) {
+	xattrs := map[string]map[string]string{
+		"lvs/vol": {
+			// The xattrs set by others are ignored
+			"user_created":          "true",
+			"longhorn.volume":       `["spec"]`,
+			"longhorn.volume.spec":  "{}",
+			"longhorn.replicas.foo": "{}",
+		},
+	}
+	server := newXattrServer(xattrs)
+	store, closeFn := newTestStore(c, server, "longhorn.replica")
+	defer closeFn()
+
+	values, err := store.GetAll("lvs/vol")
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, map[string]json.RawMessage{})
+
+	c.Assert(store.Set("lvs/vol", "spec", testSpec{Size: 1024}), IsNil)
+	c.Assert(store.CompareAndSet("lvs/vol", "generation", nil, 1), IsNil)
+	c.Assert(store.Set("lvs/vol", "spec", testSpec{Size: 2048}), IsNil)
+
+	// Only the keys themselves are written, there is no index to keep in sync
+	setReqs := []spdktypes.BdevLvolSetXattrRequest{}
+	c.Assert(server.RequestsOf("bdev_lvol_set_xattr", &setReqs), IsNil)
+	c.Assert(setReqs, HasLen, 3)
+	for _, req := range setReqs {
+		c.Assert(req.XattrName, Matches, `longhorn\.replica\.(spec|generation)`)
+	}
+
+	values, err = store.GetAll("lvs/vol")
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, map[string]json.RawMessage{
+		"spec":       json.RawMessage(`{"size":2048,"frontend":""}`),
+		"generation": json.RawMessage(`1`),
+	})
+
+	// The values are read in bulk rather than one by one
+	getReqs := []spdktypes.BdevLvolGetXattrRequest{}
+	c.Assert(server.RequestsOf("bdev_lvol_get_xattr", &getReqs), IsNil)
+	for _, req := range getReqs {
+		c.Assert(req.XattrName, Not(Equals), "longhorn.replica.spec")
+	}
+
+	snapshotXattrs, err := store.Xattrs(map[string]interface{}{"spec": testSpec{Size: 2048}, "generation": 1})
+	c.Assert(err, IsNil)
+	c.Assert(snapshotXattrs, HasLen, 2)
+	xattrs["lvs/snap"] = map[string]string{}
+	for _, xattr := range snapshotXattrs {
+		xattrs["lvs/snap"][xattr.Name] = xattr.Value
+	}
+	snapshotValues, err := store.GetAll("lvs/snap")
+	c.Assert(err, IsNil)
+	c.Assert(snapshotValues, DeepEquals, values)
+
+	_, err = store.GetAll("lvs/nonexistent")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,301 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+)
+
+type WatermarkLevel string
+
+const (
+	WatermarkLevelNormal = WatermarkLevel("normal")
+	WatermarkLevelSoft   = WatermarkLevel("soft")
+	WatermarkLevelHard   = WatermarkLevel("hard")
+)
+
+const (
+	DefaultLvstoreWatchInterval   = 30 * time.Second
+	DefaultSoftWatermarkPercent   = 20
+	DefaultHardWatermarkPercent   = 10
+	DefaultWatermarkHysteresis    = 5
+	DefaultWebhookTimeout         = 10 * time.Second
+	lvstoreWatermarkEventChanSize = 64
+)
+
+// LvstoreWatermarkEvent is emitted when the free space of a lvstore crosses a watermark.
+type LvstoreWatermarkEvent struct {
+	LvsName       string         `json:"lvs_name"`
+	LvsUUID       string         `json:"lvs_uuid"`
+	Level         WatermarkLevel `json:"level"`
+	PreviousLevel WatermarkLevel `json:"previous_level"`
+	FreeBytes     uint64         `json:"free_bytes"`
+	TotalBytes    uint64         `json:"total_bytes"`
+	FreePercent   float64        `json:"free_percent"`
+	Timestamp     time.Time      `json:"timestamp"`
+}
+
+// LvstoreWatcherOptions are the options of NewLvstoreWatcher.
+type LvstoreWatcherOptions struct {
+	// LvsName is optional. All lvstores are watched if this is not specified.
+	LvsName string
+	// Interval is how often the lvstores are polled. DefaultLvstoreWatchInterval by default.
+	Interval time.Duration
+
+	// SoftWatermarkPercent and HardWatermarkPercent are the free space percentages below which the level becomes soft and hard.
+	// DefaultSoftWatermarkPercent and DefaultHardWatermarkPercent by default.
+	SoftWatermarkPercent float64
+	HardWatermarkPercent float64
+	// HysteresisPercent is how much the free space percentage must rise above a watermark before the level is lowered again.
+	// It avoids flapping events when the free space hovers around a watermark. DefaultWatermarkHysteresis if this is nil,
+	// and 0 disables the hysteresis.
+	HysteresisPercent *float64
+
+	// Callback is optional. It is called with each event in addition to sending the event to the channel.
+	// The calls are made in order from a separate goroutine, so that a slow callback does not delay the polling.
+	// Events are dropped rather than queued without limit if the callback falls behind.
+	Callback func(LvstoreWatermarkEvent)
+}
+
+// LvstoreWatcher polls lvstores and emits events when their free space crosses the watermarks.
+type LvstoreWatcher struct {
+	spdkCli *client.Client
+	opts    LvstoreWatcherOptions
+
+	hysteresis float64
+
+	levels     map[string]WatermarkLevel
+	eventCh    chan LvstoreWatermarkEvent
+	callbackCh chan LvstoreWatermarkEvent
+}
+
+func NewLvstoreWatcher(spdkCli *client.Client, opts LvstoreWatcherOptions) (*LvstoreWatcher, error) {
+	if spdkCli == nil {
+		return nil, fmt.Errorf("empty SPDK client for lvstore watcher creation")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultLvstoreWatchInterval
+	}
+	if opts.SoftWatermarkPercent <= 0 {
+		opts.SoftWatermarkPercent = DefaultSoftWatermarkPercent
+	}
+	if opts.HardWatermarkPercent <= 0 {
+		opts.HardWatermarkPercent = DefaultHardWatermarkPercent
+	}
+	hysteresis := float64(DefaultWatermarkHysteresis)
+	if opts.HysteresisPercent != nil {
+		hysteresis = *opts.HysteresisPercent
+	}
+	if hysteresis < 0 {
+		return nil, fmt.Errorf("invalid negative hysteresis %v for lvstore watcher creation", hysteresis)
+	}
+	if opts.HardWatermarkPercent > opts.SoftWatermarkPercent || opts.SoftWatermarkPercent > 100 {
+		return nil, fmt.Errorf("invalid watermarks for lvstore watcher creation, hard %v%% should not be larger than soft %v%%, which should not be larger than 100%%",
+			opts.HardWatermarkPercent, opts.SoftWatermarkPercent)
+	}
+
+	w := &LvstoreWatcher{
+		spdkCli: spdkCli,
+		opts:    opts,
+
+		hysteresis: hysteresis,
+
+		levels:  map[string]WatermarkLevel{},
+		eventCh: make(chan LvstoreWatermarkEvent, lvstoreWatermarkEventChanSize),
+	}
+	if opts.Callback != nil {
+		w.callbackCh = make(chan LvstoreWatermarkEvent, lvstoreWatermarkEventChanSize)
+	}
+	return w, nil
+}
+
+// Events returns the channel on which the events are delivered.
+// Events are dropped rather than blocking the polling if the receiver falls behind,
+// and the channel is closed once Run returns.
+func (w *LvstoreWatcher) Events() <-chan LvstoreWatermarkEvent {
+	return w.eventCh
+}
+
+// Run polls the lvstores until the context is done.
+// The first poll emits an event for each lvstore already below a watermark.
+// The events queued for the callback are still delivered in the background after Run returns.
+func (w *LvstoreWatcher) Run(ctx context.Context) error {
+	defer close(w.eventCh)
+
+	if w.callbackCh != nil {
+		go w.runCallback()
+		defer close(w.callbackCh)
+	}
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(); err != nil {
+			logrus.WithError(err).Warn("Failed to poll lvstores for the watermarks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *LvstoreWatcher) runCallback() {
+	for event := range w.callbackCh {
+		w.opts.Callback(event)
+	}
+}
+
+func (w *LvstoreWatcher) poll() error {
+	lvsList, err := w.spdkCli.BdevLvolGetLvstore(w.opts.LvsName, "")
+	if err != nil {
+		// The watched lvstore is deleted
+		if w.opts.LvsName == "" || !jsonrpc.IsJSONRPCRespErrorNoSuchDevice(err) {
+			return err
+		}
+		lvsList = nil
+	}
+
+	// Forget the deleted lvstores, so that the levels do not pile up and a recreated lvstore starts over
+	existing := map[string]bool{}
+	for _, lvs := range lvsList {
+		existing[lvs.UUID] = true
+	}
+	for uuid := range w.levels {
+		if !existing[uuid] {
+			delete(w.levels, uuid)
+		}
+	}
+
+	for _, lvs := range lvsList {
+		totalBytes := lvs.TotalDataClusters * lvs.ClusterSize
+		if totalBytes == 0 {
+			continue
+		}
+		freeBytes := lvs.FreeClusters * lvs.ClusterSize
+		freePercent := float64(freeBytes) * 100 / float64(totalBytes)
+
+		previous, exists := w.levels[lvs.UUID]
+		if !exists {
+			previous = WatermarkLevelNormal
+		}
+		level := evaluateWatermarkLevel(previous, freePercent, w.opts.SoftWatermarkPercent, w.opts.HardWatermarkPercent, w.hysteresis)
+		w.levels[lvs.UUID] = level
+		if level == previous {
+			continue
+		}
+
+		w.emit(LvstoreWatermarkEvent{
+			LvsName:       lvs.Name,
+			LvsUUID:       lvs.UUID,
+			Level:         level,
+			PreviousLevel: previous,
+			FreeBytes:     freeBytes,
+			TotalBytes:    totalBytes,
+			FreePercent:   freePercent,
+			Timestamp:     time.Now(),
+		})
+	}
+
+	return nil
+}
+
+func (w *LvstoreWatcher) emit(event LvstoreWatermarkEvent) {
+	log := logrus.WithFields(logrus.Fields{
+		"lvsName":     event.LvsName,
+		"level":       event.Level,
+		"freePercent": fmt.Sprintf("%.2f", event.FreePercent),
+	})
+	switch {
+	case event.Level == WatermarkLevelNormal:
+		log.Info("Lvstore free space is back to normal")
+	case watermarkSeverity[event.Level] < watermarkSeverity[event.PreviousLevel]:
+		log.Info("Lvstore free space rose above the hard watermark")
+	default:
+		log.Warn("Lvstore free space dropped below the watermark")
+	}
+
+	if w.callbackCh != nil {
+		select {
+		case w.callbackCh <- event:
+		default:
+			log.Warn("Dropped lvstore watermark event for the callback since it falls behind")
+		}
+	}
+
+	select {
+	case w.eventCh <- event:
+	default:
+		log.Warn("Dropped lvstore watermark event since the receiver falls behind")
+	}
+}
+
+var watermarkSeverity = map[WatermarkLevel]int{
+	WatermarkLevelNormal: 0,
+	WatermarkLevelSoft:   1,
+	WatermarkLevelHard:   2,
+}
+
+// evaluateWatermarkLevel returns the new level for the free space percentage.
+// A level is raised as soon as the free space drops below its watermark,
+// but it is lowered only after the free space rises above the watermark plus the hysteresis.
+func evaluateWatermarkLevel(current WatermarkLevel, freePercent, soft, hard, hysteresis float64) WatermarkLevel {
+	if freePercent < hard {
+		return WatermarkLevelHard
+	}
+	if current == WatermarkLevelHard && freePercent < hard+hysteresis {
+		return WatermarkLevelHard
+	}
+	if freePercent < soft {
+		return WatermarkLevelSoft
+	}
+	if current != WatermarkLevelNormal && freePercent < soft+hysteresis {
+		return WatermarkLevelSoft
+	}
+	return WatermarkLevelNormal
+}
+
+// NewWebhookCallback returns a callback posting each event as JSON to the URL.
+// Failures are logged only, so that an unreachable webhook does not stop the watcher.
+func NewWebhookCallback(url string, timeout time.Duration) func(LvstoreWatermarkEvent) {
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	httpCli := &http.Client{Timeout: timeout}
+
+	return func(event LvstoreWatermarkEvent) {
+		if err := postJSON(httpCli, url, event); err != nil {
+			logrus.WithError(err).Warnf("Failed to post lvstore watermark event to webhook %s", url)
+		}
+	}
+}
+
+func postJSON(httpCli *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpCli.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc/jsonrpctest"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+func (s *TestSuite) TestEvaluateWatermarkLevel(c *C) {
+	soft, hard, hysteresis := float64(20), float64(10), float64(5)
+
+	testCases := []struct {
+		current     WatermarkLevel
+		freePercent float64
+		expected    WatermarkLevel
+	}{
+		{WatermarkLevelNormal, 50, WatermarkLevelNormal},
+		{WatermarkLevelNormal, 19, WatermarkLevelSoft},
+		{WatermarkLevelNormal, 5, WatermarkLevelHard},
+		// Stay soft until the free space rises above soft + hysteresis
+		{WatermarkLevelSoft, 22, WatermarkLevelSoft},
+		{WatermarkLevelSoft, 25, WatermarkLevelNormal},
+		{WatermarkLevelSoft, 9, WatermarkLevelHard},
+		// Stay hard until the free space rises above hard + hysteresis
+		{WatermarkLevelHard, 12, WatermarkLevelHard},
+		{WatermarkLevelHard, 16, WatermarkLevelSoft},
+		{WatermarkLevelHard, 24, WatermarkLevelSoft},
+		{WatermarkLevelHard, 30, WatermarkLevelNormal},
+	}
+
+	for _, tc := range testCases {
+		level := evaluateWatermarkLevel(tc.current, tc.freePercent, soft, hard, hysteresis)
+		c.Assert(level, Equals, tc.expected, Commentf("current %v, free %v%%", tc.current, tc.freePercent))
+	}
+}
+
+// fakeLvstores serves bdev_lvol_get_lvstores with 100 clusters per lvstore, of which the given numbers are free.
+type fakeLvstores struct {
+	sync.Mutex
+
+	freeClusters map[string]uint64
+}
+
+func (f *fakeLvstores) set(name string, freeClusters uint64) {
+	f.Lock()
+	defer f.Unlock()
+	f.freeClusters[name] = freeClusters
+}
+
+func (f *fakeLvstores) remove(name string) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.freeClusters, name)
+}
+
+func newTestLvstoreWatcher(c *C, opts LvstoreWatcherOptions) (*LvstoreWatcher, *fakeLvstores, func()) {
+	lvstores := &fakeLvstores{freeClusters: map[string]uint64{}}
+	server := jsonrpctest.NewServer(map[string]jsonrpctest.Handler{
+		"bdev_lvol_get_lvstores": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolGetLvstoreRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			lvstores.Lock()
+			defer lvstores.Unlock()
+			lvsList := []spdktypes.LvstoreInfo{}
+			for name, freeClusters := range lvstores.freeClusters {
+				if req.LvsName != "" && req.LvsName != name {
+					continue
+				}
+				lvsList = append(lvsList, spdktypes.LvstoreInfo{
+					UUID:              name + "-uuid",
+					Name:              name,
+					TotalDataClusters: 100,
+					FreeClusters:      freeClusters,
+					ClusterSize:       1024,
+				})
+			}
+			if req.LvsName != "" && len(lvsList) == 0 {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchDevice, Message: "No such device"}
+			}
+			return lvsList, nil
+		},
+	})
+	conn, closeConn := server.Dial()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w, err := NewLvstoreWatcher(client.NewClientWithConn(ctx, conn), opts)
+	c.Assert(err, IsNil)
+	return w, lvstores, func() {
+		cancel()
+		closeConn()
+	}
+}
+
+// pollEvents polls once and returns the levels of the emitted events keyed by the lvstore name.
+func pollEvents(c *C, w *LvstoreWatcher) map[string]WatermarkLevel {
+	c.Assert(w.poll(), IsNil)
+
+	levels := map[string]WatermarkLevel{}
+	for {
+		select {
+		case event := <-w.Events():
+			levels[event.LvsName] = event.Level
+		default:
+			return levels
+		}
+	}
+}
+
+func (s *TestSuite) TestLvstoreWatcherHysteresis(c *C) {
+	// The default hysteresis keeps the level until the free space rises above 25%
+	w, lvstores, closeFn := newTestLvstoreWatcher(c, LvstoreWatcherOptions{})
+	defer closeFn()
+
+	lvstores.set("lvs", 19)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{"lvs": WatermarkLevelSoft})
+	lvstores.set("lvs", 21)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{})
+	lvstores.set("lvs", 26)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{"lvs": WatermarkLevelNormal})
+
+	// Zero disables the hysteresis rather than falling back to the default
+	hysteresis := float64(0)
+	w, lvstores, closeFn = newTestLvstoreWatcher(c, LvstoreWatcherOptions{HysteresisPercent: &hysteresis})
+	defer closeFn()
+
+	lvstores.set("lvs", 9)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{"lvs": WatermarkLevelHard})
+	lvstores.set("lvs", 11)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{"lvs": WatermarkLevelSoft})
+	lvstores.set("lvs", 21)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{"lvs": WatermarkLevelNormal})
+
+	hysteresis = -1
+	_, err := NewLvstoreWatcher(&client.Client{}, LvstoreWatcherOptions{HysteresisPercent: &hysteresis})
+	c.Assert(err, ErrorMatches, "invalid negative hysteresis .*")
+}
+
+func (s *TestSuite) TestLvstoreWatcherPruneLevels(c *C) {
+	w, lvstores, closeFn := newTestLvstoreWatcher(c, LvstoreWatcherOptions{})
+	defer closeFn()
+
+	lvstores.set("lvs1", 5)
+	lvstores.set("lvs2", 50)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{"lvs1": WatermarkLevelHard})
+	c.Assert(w.levels, HasLen, 2)
+
+	// A deleted lvstore is forgotten, and it starts over once recreated
+	lvstores.remove("lvs1")
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{})
+	c.Assert(w.levels, DeepEquals, map[string]WatermarkLevel{"lvs2-uuid": WatermarkLevelNormal})
+	lvstores.set("lvs1", 5)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{"lvs1": WatermarkLevelHard})
+
+	// The same applies to the single watched lvstore
+	w, lvstores, closeFn = newTestLvstoreWatcher(c, LvstoreWatcherOptions{LvsName: "lvs1"})
+	defer closeFn()
+
+	lvstores.set("lvs1", 5)
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{"lvs1": WatermarkLevelHard})
+	lvstores.remove("lvs1")
+	c.Assert(pollEvents(c, w), DeepEquals, map[string]WatermarkLevel{})
+	c.Assert(w.levels, HasLen, 0)
+}
+
+func (s *TestSuite) TestLvstoreWatcherCallback(c *C) {
+	unblockCh := make(chan struct{})
+	callbackCh := make(chan LvstoreWatermarkEvent, 2)
+	w, lvstores, closeFn := newTestLvstoreWatcher(c, LvstoreWatcherOptions{
+		Interval: 10 * time.Millisecond,
+		Callback: func(event LvstoreWatermarkEvent) {
+			<-unblockCh
+			callbackCh <- event
+		},
+	})
+	defer closeFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lvstores.set("lvs", 5)
+	go func() {
+		_ = w.Run(ctx)
+	}()
+
+	// The polling goes on while the callback is blocked
+	event := <-w.Events()
+	c.Assert(event.Level, Equals, WatermarkLevelHard)
+	lvstores.set("lvs", 50)
+	select {
+	case event = <-w.Events():
+		c.Assert(event.Level, Equals, WatermarkLevelNormal)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the event while the callback is blocked")
+	}
+
+	close(unblockCh)
+	for _, expected := range []WatermarkLevel{WatermarkLevelHard, WatermarkLevelNormal} {
+		select {
+		case event = <-callbackCh:
+			c.Assert(event.Level, Equals, expected)
+		case <-time.After(5 * time.Second):
+			c.Fatal("timeout waiting for the callback")
+		}
+	}
+}
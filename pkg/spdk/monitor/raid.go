@@ -0,0 +1,259 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+type RaidEventType string
+
+const (
+	// RaidEventTypeDegraded means the number of operational base bdevs drops.
+	RaidEventTypeDegraded = RaidEventType("degraded")
+	// RaidEventTypeRecovered means the number of operational base bdevs rises again.
+	RaidEventTypeRecovered = RaidEventType("recovered")
+	// RaidEventTypeBaseBdevLost means a base bdev slot is no longer configured.
+	RaidEventTypeBaseBdevLost = RaidEventType("base_bdev_lost")
+	// RaidEventTypeBaseBdevRecovered means a base bdev slot is configured again.
+	RaidEventTypeBaseBdevRecovered = RaidEventType("base_bdev_recovered")
+	// RaidEventTypeOffline means the raid bdev moves to offline.
+	RaidEventTypeOffline = RaidEventType("offline")
+	// RaidEventTypeOnline means the raid bdev moves back to online.
+	RaidEventTypeOnline = RaidEventType("online")
+)
+
+const (
+	DefaultRaidMonitorInterval = 5 * time.Second
+	raidEventChanSize          = 64
+)
+
+// RaidEvent is emitted when the health of a raid bdev changes.
+type RaidEvent struct {
+	RaidName string        `json:"raid_name"`
+	Type     RaidEventType `json:"type"`
+	// BaseBdev is set for the base bdev events only.
+	BaseBdev string `json:"base_bdev,omitempty"`
+
+	State                   string    `json:"state"`
+	PreviousState           string    `json:"previous_state"`
+	NumBaseBdevs            uint8     `json:"num_base_bdevs"`
+	NumBaseBdevsOperational uint8     `json:"num_base_bdevs_operational"`
+	PreviousOperational     uint8     `json:"previous_operational"`
+	Timestamp               time.Time `json:"timestamp"`
+}
+
+// RaidMonitorOptions are the options of NewRaidMonitor.
+type RaidMonitorOptions struct {
+	// RaidName is optional. All raid bdevs are monitored if this is not specified.
+	RaidName string
+	// Interval is how often the raid bdevs are polled. DefaultRaidMonitorInterval by default.
+	Interval time.Duration
+
+	// Callback is optional. It is called with each event in addition to sending the event to the channel.
+	// The calls are made in order from a separate goroutine, so that a slow callback does not delay the polling.
+	// Events are dropped rather than queued without limit if the callback falls behind.
+	Callback func(RaidEvent)
+}
+
+// RaidMonitor polls raid bdevs and emits events when they become degraded or offline, and when they recover.
+type RaidMonitor struct {
+	spdkCli *client.Client
+	opts    RaidMonitorOptions
+
+	snapshots  map[string]*spdktypes.BdevRaidInfo
+	eventCh    chan RaidEvent
+	callbackCh chan RaidEvent
+}
+
+func NewRaidMonitor(spdkCli *client.Client, opts RaidMonitorOptions) (*RaidMonitor, error) {
+	if spdkCli == nil {
+		return nil, fmt.Errorf("empty SPDK client for raid monitor creation")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultRaidMonitorInterval
+	}
+
+	m := &RaidMonitor{
+		spdkCli: spdkCli,
+		opts:    opts,
+
+		snapshots: map[string]*spdktypes.BdevRaidInfo{},
+		eventCh:   make(chan RaidEvent, raidEventChanSize),
+	}
+	if opts.Callback != nil {
+		m.callbackCh = make(chan RaidEvent, raidEventChanSize)
+	}
+	return m, nil
+}
+
+// Events returns the channel on which the events are delivered.
+// Events are dropped rather than blocking the polling if the receiver falls behind,
+// and the channel is closed once Run returns.
+func (m *RaidMonitor) Events() <-chan RaidEvent {
+	return m.eventCh
+}
+
+// Run polls the raid bdevs until the context is done.
+// The first poll emits an event for each raid bdev already degraded or offline.
+func (m *RaidMonitor) Run(ctx context.Context) error {
+	defer close(m.eventCh)
+
+	if m.callbackCh != nil {
+		go m.runCallback()
+		defer close(m.callbackCh)
+	}
+
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.poll(); err != nil {
+			logrus.WithError(err).Warn("Failed to poll raid bdevs for the health")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *RaidMonitor) runCallback() {
+	for event := range m.callbackCh {
+		m.opts.Callback(event)
+	}
+}
+
+func (m *RaidMonitor) poll() error {
+	raidList, err := m.spdkCli.BdevRaidGetInfoByCategory(spdktypes.BdevRaidCategoryAll)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for idx := range raidList {
+		raid := &raidList[idx]
+		if m.opts.RaidName != "" && raid.Name != m.opts.RaidName {
+			continue
+		}
+		seen[raid.Name] = true
+
+		for _, event := range compareRaidInfo(m.snapshots[raid.Name], raid, time.Now()) {
+			m.emit(event)
+		}
+		m.snapshots[raid.Name] = raid
+	}
+
+	// Forget the deleted raid bdevs so that a recreated one starts over
+	for name := range m.snapshots {
+		if !seen[name] {
+			delete(m.snapshots, name)
+		}
+	}
+
+	return nil
+}
+
+func (m *RaidMonitor) emit(event RaidEvent) {
+	log := logrus.WithFields(logrus.Fields{
+		"raidName":    event.RaidName,
+		"type":        event.Type,
+		"state":       event.State,
+		"operational": fmt.Sprintf("%d/%d", event.NumBaseBdevsOperational, event.NumBaseBdevs),
+	})
+	if event.BaseBdev != "" {
+		log = log.WithField("baseBdev", event.BaseBdev)
+	}
+	switch event.Type {
+	case RaidEventTypeRecovered, RaidEventTypeBaseBdevRecovered, RaidEventTypeOnline:
+		log.Info("Raid bdev health is recovering")
+	default:
+		log.Warn("Raid bdev health is deteriorating")
+	}
+
+	if m.callbackCh != nil {
+		select {
+		case m.callbackCh <- event:
+		default:
+			log.Warn("Dropped raid event for the callback since it falls behind")
+		}
+	}
+
+	select {
+	case m.eventCh <- event:
+	default:
+		log.Warn("Dropped raid event since the receiver falls behind")
+	}
+}
+
+// compareRaidInfo returns the events between two snapshots of a raid bdev.
+// A nil previous snapshot means the raid bdev is seen for the first time,
+// in which case only the degraded and offline events are reported.
+func compareRaidInfo(previous, current *spdktypes.BdevRaidInfo, now time.Time) []RaidEvent {
+	newEvent := func(eventType RaidEventType, baseBdev string) RaidEvent {
+		event := RaidEvent{
+			RaidName:                current.Name,
+			Type:                    eventType,
+			BaseBdev:                baseBdev,
+			State:                   current.State,
+			NumBaseBdevs:            current.NumBaseBdevs,
+			NumBaseBdevsOperational: current.NumBaseBdevsOperational,
+			Timestamp:               now,
+		}
+		if previous != nil {
+			event.PreviousState = previous.State
+			event.PreviousOperational = previous.NumBaseBdevsOperational
+		}
+		return event
+	}
+
+	events := []RaidEvent{}
+	if previous == nil {
+		if current.State == spdktypes.BdevRaidCategoryOffline {
+			events = append(events, newEvent(RaidEventTypeOffline, ""))
+		}
+		if current.NumBaseBdevsOperational < current.NumBaseBdevs {
+			events = append(events, newEvent(RaidEventTypeDegraded, ""))
+		}
+		return events
+	}
+
+	if current.State == spdktypes.BdevRaidCategoryOffline && previous.State != spdktypes.BdevRaidCategoryOffline {
+		events = append(events, newEvent(RaidEventTypeOffline, ""))
+	}
+	if current.State == spdktypes.BdevRaidCategoryOnline && previous.State == spdktypes.BdevRaidCategoryOffline {
+		events = append(events, newEvent(RaidEventTypeOnline, ""))
+	}
+
+	if current.NumBaseBdevsOperational < previous.NumBaseBdevsOperational {
+		events = append(events, newEvent(RaidEventTypeDegraded, ""))
+	}
+	if current.NumBaseBdevsOperational > previous.NumBaseBdevsOperational {
+		events = append(events, newEvent(RaidEventTypeRecovered, ""))
+	}
+
+	// The base bdev list is indexed by slot. A removed base bdev leaves an unconfigured slot without a name,
+	// hence the name in the previous snapshot is reported for a lost one.
+	for idx := range current.BaseBdevsList {
+		if idx >= len(previous.BaseBdevsList) {
+			break
+		}
+		previousBaseBdev, currentBaseBdev := previous.BaseBdevsList[idx], current.BaseBdevsList[idx]
+		if previousBaseBdev.IsConfigured && !currentBaseBdev.IsConfigured {
+			events = append(events, newEvent(RaidEventTypeBaseBdevLost, previousBaseBdev.Name))
+		}
+		if !previousBaseBdev.IsConfigured && currentBaseBdev.IsConfigured {
+			events = append(events, newEvent(RaidEventTypeBaseBdevRecovered, currentBaseBdev.Name))
+		}
+	}
+
+	return events
+}
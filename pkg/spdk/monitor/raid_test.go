@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc/jsonrpctest"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+func newTestRaidInfo(state string, configured ...bool) *spdktypes.BdevRaidInfo {
+	raid := &spdktypes.BdevRaidInfo{
+		Name:         "raid01",
+		State:        state,
+		RaidLevel:    spdktypes.BdevRaidLevelRaid1,
+		NumBaseBdevs: uint8(len(configured)),
+	}
+	for idx, isConfigured := range configured {
+		baseBdev := spdktypes.BaseBdev{}
+		if isConfigured {
+			baseBdev.Name = []string{"lvs/lvol0", "lvs/lvol1", "lvs/lvol2"}[idx]
+			baseBdev.IsConfigured = true
+			raid.NumBaseBdevsOperational++
+			raid.NumBaseBdevsDiscovered++
+		}
+		raid.BaseBdevsList = append(raid.BaseBdevsList, baseBdev)
+	}
+	return raid
+}
+
+func (s *TestSuite) TestCompareRaidInfo(c *C) {
+	now := time.Now()
+
+	testCases := []struct {
+		previous *spdktypes.BdevRaidInfo
+		current  *spdktypes.BdevRaidInfo
+		expected []RaidEventType
+		baseBdev string
+	}{
+		// First seen
+		{nil, newTestRaidInfo("online", true, true), []RaidEventType{}, ""},
+		{nil, newTestRaidInfo("online", true, false), []RaidEventType{RaidEventTypeDegraded}, ""},
+		{nil, newTestRaidInfo("offline", false, false), []RaidEventType{RaidEventTypeOffline, RaidEventTypeDegraded}, ""},
+		// No change
+		{newTestRaidInfo("online", true, true), newTestRaidInfo("online", true, true), []RaidEventType{}, ""},
+		// Lose a base bdev
+		{newTestRaidInfo("online", true, true), newTestRaidInfo("online", true, false), []RaidEventType{RaidEventTypeDegraded, RaidEventTypeBaseBdevLost}, "lvs/lvol1"},
+		// Get a base bdev back
+		{newTestRaidInfo("online", false, true), newTestRaidInfo("online", true, true), []RaidEventType{RaidEventTypeRecovered, RaidEventTypeBaseBdevRecovered}, "lvs/lvol0"},
+		// Go offline and back
+		{newTestRaidInfo("online", true, false), newTestRaidInfo("offline", false, false), []RaidEventType{RaidEventTypeOffline, RaidEventTypeDegraded, RaidEventTypeBaseBdevLost}, "lvs/lvol0"},
+		{newTestRaidInfo("offline", false, false), newTestRaidInfo("online", true, false), []RaidEventType{RaidEventTypeOnline, RaidEventTypeRecovered, RaidEventTypeBaseBdevRecovered}, "lvs/lvol0"},
+	}
+
+	for idx, testCase := range testCases {
+		comment := Commentf("test case %d", idx)
+
+		events := compareRaidInfo(testCase.previous, testCase.current, now)
+		eventTypes := []RaidEventType{}
+		for _, event := range events {
+			eventTypes = append(eventTypes, event.Type)
+			c.Assert(event.RaidName, Equals, "raid01", comment)
+			c.Assert(event.Timestamp, Equals, now, comment)
+			if event.Type == RaidEventTypeBaseBdevLost || event.Type == RaidEventTypeBaseBdevRecovered {
+				c.Assert(event.BaseBdev, Equals, testCase.baseBdev, comment)
+			}
+		}
+		c.Assert(eventTypes, DeepEquals, testCase.expected, comment)
+	}
+}
+
+// fakeRaids serves bdev_raid_get_bdevs with the current info of the raid bdevs.
+type fakeRaids struct {
+	sync.Mutex
+
+	raids []spdktypes.BdevRaidInfo
+}
+
+func (f *fakeRaids) set(raids ...*spdktypes.BdevRaidInfo) {
+	f.Lock()
+	defer f.Unlock()
+	f.raids = []spdktypes.BdevRaidInfo{}
+	for _, raid := range raids {
+		f.raids = append(f.raids, *raid)
+	}
+}
+
+func newTestRaidMonitor(c *C, opts RaidMonitorOptions) (*RaidMonitor, *fakeRaids, func()) {
+	raids := &fakeRaids{}
+	server := jsonrpctest.NewServer(map[string]jsonrpctest.Handler{
+		"bdev_raid_get_bdevs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			raids.Lock()
+			defer raids.Unlock()
+			return raids.raids, nil
+		},
+	})
+	conn, closeConn := server.Dial()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m, err := NewRaidMonitor(client.NewClientWithConn(ctx, conn), opts)
+	c.Assert(err, IsNil)
+	return m, raids, func() {
+		cancel()
+		closeConn()
+	}
+}
+
+func (s *TestSuite) TestRaidMonitorCallback(c *C) {
+	unblockCh := make(chan struct{})
+	callbackCh := make(chan RaidEvent, 3)
+	m, raids, closeFn := newTestRaidMonitor(c, RaidMonitorOptions{
+		Interval: 10 * time.Millisecond,
+		Callback: func(event RaidEvent) {
+			<-unblockCh
+			callbackCh <- event
+		},
+	})
+	defer closeFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	raids.set(newTestRaidInfo("online", true, false))
+	go func() {
+		_ = m.Run(ctx)
+	}()
+
+	// The polling goes on while the callback is blocked
+	event := <-m.Events()
+	c.Assert(event.Type, Equals, RaidEventTypeDegraded)
+	raids.set(newTestRaidInfo("online", true, true))
+	select {
+	case event = <-m.Events():
+		c.Assert(event.Type, Equals, RaidEventTypeRecovered)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the event while the callback is blocked")
+	}
+
+	close(unblockCh)
+	for _, expected := range []RaidEventType{RaidEventTypeDegraded, RaidEventTypeRecovered, RaidEventTypeBaseBdevRecovered} {
+		select {
+		case event = <-callbackCh:
+			c.Assert(event.Type, Equals, expected)
+		case <-time.After(5 * time.Second):
+			c.Fatal("timeout waiting for the callback")
+		}
+	}
+}
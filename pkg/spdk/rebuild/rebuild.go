@@ -0,0 +1,315 @@
+package rebuild
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/shallowcopy"
+	"github.com/longhorn/go-spdk-helper/pkg/types"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+const (
+	// RebuildSource is the xattr recording the UUID of the source snapshot a rebuilt snapshot is copied from.
+	// It is set when the destination snapshot is created, which makes it the checkpoint of the rebuild.
+	RebuildSource = "rebuild_source"
+
+	rebuildingLvolSuffix = "-rebuilding"
+)
+
+// Rebuilder copies a snapshot chain from a source node into a lvstore on a destination node.
+//
+// For each snapshot in the chain, a temporary lvol is created in the destination lvstore and exposed via NVMe/TCP.
+// The source node attaches it as an NVMe bdev and shallow copies the snapshot to it.
+// Then the temporary lvol is relinked to the previously rebuilt snapshot and snapshotted with the source snapshot name.
+type Rebuilder struct {
+	srcCli *client.Client
+	dstCli *client.Client
+
+	dstLvsName string
+	dstIP      string
+	dstPort    string
+
+	copyManager *shallowcopy.Manager
+
+	log logrus.FieldLogger
+}
+
+// NewRebuilder creates a rebuilder.
+//
+//	"srcCli": Required. SPDK client of the node holding the source snapshot chain.
+//
+//	"dstCli": Required. SPDK client of the node to rebuild the chain on.
+//
+//	"dstLvsName": Required. Name of the lvstore to rebuild the chain in.
+//
+//	"dstIP" and "dstPort": Required. The NVMe/TCP address the destination node uses to expose the lvols being rebuilt.
+func NewRebuilder(srcCli, dstCli *client.Client, dstLvsName, dstIP, dstPort string) (*Rebuilder, error) {
+	if srcCli == nil || dstCli == nil {
+		return nil, fmt.Errorf("both source and destination SPDK clients are required for rebuilder creation")
+	}
+	if dstLvsName == "" {
+		return nil, fmt.Errorf("empty destination lvstore name for rebuilder creation")
+	}
+	if dstIP == "" || dstPort == "" {
+		return nil, fmt.Errorf("empty destination address for rebuilder creation")
+	}
+
+	copyManager, err := shallowcopy.NewManager(srcCli, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rebuilder{
+		srcCli: srcCli,
+		dstCli: dstCli,
+
+		dstLvsName: dstLvsName,
+		dstIP:      dstIP,
+		dstPort:    dstPort,
+
+		copyManager: copyManager,
+
+		log: logrus.WithFields(logrus.Fields{
+			"dstLvsName": dstLvsName,
+			"dstAddress": net.JoinHostPort(dstIP, dstPort),
+		}),
+	}, nil
+}
+
+// GetSnapshotChain returns the snapshots of a lvol ordered from the oldest ancestor to the direct parent.
+//
+//	"name": Required. UUID or alias of the lvol. The alias of a lvol is <LVSTORE NAME>/<LVOL NAME>.
+func GetSnapshotChain(spdkCli *client.Client, name string) (chain []string, err error) {
+	lvol, err := spdkCli.BdevLvolGetByName(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(lvol.Aliases) == 0 {
+		return nil, fmt.Errorf("cannot find the alias of lvol %s", name)
+	}
+	lvsName := spdktypes.GetLvsNameFromAlias(lvol.Aliases[0])
+
+	for parent := lvol.DriverSpecific.Lvol.BaseSnapshot; parent != ""; {
+		snapshot, err := spdkCli.BdevLvolGetByName(spdktypes.GetLvolAlias(lvsName, parent), 0)
+		if err != nil {
+			return nil, err
+		}
+		chain = append([]string{snapshot.UUID}, chain...)
+		parent = snapshot.DriverSpecific.Lvol.BaseSnapshot
+	}
+
+	return chain, nil
+}
+
+// Rebuild copies the snapshot chain into the destination lvstore and returns the UUIDs of the rebuilt snapshots.
+// The destination snapshots keep the names and the user xattrs of the source ones.
+//
+// Snapshots already rebuilt by a previous interrupted call are detected via the RebuildSource xattr and skipped,
+// so calling it again with the same chain resumes the rebuild.
+// Canceling ctx stops the rebuild once the shallow copy in progress, if any, finishes.
+//
+//	"srcSnapshots": Required. UUIDs or aliases of the source snapshots, ordered from the oldest ancestor.
+func (r *Rebuilder) Rebuild(ctx context.Context, srcSnapshots []string) (dstSnapshots []string, err error) {
+	rebuilt, err := r.getRebuiltSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	parent := ""
+	for idx, name := range srcSnapshots {
+		srcSnapshot, err := r.srcCli.BdevLvolGetByName(name, 0)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get source snapshot %s", name)
+		}
+		if !srcSnapshot.DriverSpecific.Lvol.Snapshot {
+			return nil, fmt.Errorf("source lvol %s is not a snapshot", name)
+		}
+
+		log := r.log.WithFields(logrus.Fields{
+			"index":       idx,
+			"srcSnapshot": srcSnapshot.UUID,
+		})
+
+		dstSnapshot, exists := rebuilt[srcSnapshot.UUID]
+		if exists {
+			log.Infof("Skipping copying snapshot since it has been rebuilt as %s", dstSnapshot)
+		} else {
+			log.Info("Rebuilding snapshot")
+			if dstSnapshot, err = r.rebuildSnapshot(ctx, &srcSnapshot, parent); err != nil {
+				return nil, errors.Wrapf(err, "failed to rebuild snapshot %s", srcSnapshot.UUID)
+			}
+		}
+
+		if err := r.verifySnapshot(srcSnapshot.UUID, dstSnapshot); err != nil {
+			return nil, err
+		}
+		log.Infof("Rebuilt and verified snapshot %s", dstSnapshot)
+
+		dstSnapshots = append(dstSnapshots, dstSnapshot)
+		parent = dstSnapshot
+	}
+
+	return dstSnapshots, nil
+}
+
+// getRebuiltSnapshots returns the snapshots in the destination lvstore with the RebuildSource xattr,
+// keyed by the source snapshot UUID.
+func (r *Rebuilder) getRebuiltSnapshots() (map[string]string, error) {
+	lvolList, err := r.dstCli.BdevLvolGetWithFilter("", 0, func(b *spdktypes.BdevInfo) bool {
+		return b.DriverSpecific.Lvol.Snapshot && len(b.Aliases) > 0 &&
+			spdktypes.GetLvsNameFromAlias(b.Aliases[0]) == r.dstLvsName
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rebuilt := map[string]string{}
+	for _, lvol := range lvolList {
+		source, err := r.dstCli.BdevLvolGetXattr(lvol.UUID, RebuildSource)
+		if err != nil {
+			if jsonrpc.IsJSONRPCRespErrorNoSuchFile(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get xattr %s of snapshot %s", RebuildSource, lvol.UUID)
+		}
+		if source == "" {
+			continue
+		}
+		rebuilt[source] = lvol.UUID
+	}
+	return rebuilt, nil
+}
+
+func (r *Rebuilder) rebuildSnapshot(ctx context.Context, srcSnapshot *spdktypes.BdevInfo, parent string) (dstSnapshot string, err error) {
+	snapshotName := spdktypes.GetLvolNameFromAlias(srcSnapshot.Aliases[0])
+	lvolName := snapshotName + rebuildingLvolSuffix
+	lvolAlias := spdktypes.GetLvolAlias(r.dstLvsName, lvolName)
+
+	// Clean up the leftover of an interrupted rebuild
+	if _, err := r.dstCli.BdevLvolDelete(lvolAlias); err != nil && !jsonrpc.IsJSONRPCRespErrorNoSuchDevice(err) {
+		return "", errors.Wrapf(err, "failed to clean up rebuilding lvol %s", lvolAlias)
+	}
+
+	sizeInMib := (uint64(srcSnapshot.BlockSize)*srcSnapshot.NumBlocks + types.MiB - 1) / types.MiB
+	if _, err := r.dstCli.BdevLvolCreate(r.dstLvsName, "", lvolName, sizeInMib, "", true); err != nil {
+		return "", errors.Wrapf(err, "failed to create rebuilding lvol %s", lvolAlias)
+	}
+	defer func() {
+		if _, deleteErr := r.dstCli.BdevLvolDelete(lvolAlias); deleteErr != nil && !jsonrpc.IsJSONRPCRespErrorNoSuchDevice(deleteErr) {
+			r.log.WithError(deleteErr).Warnf("Failed to delete rebuilding lvol %s", lvolAlias)
+		}
+	}()
+
+	if err := r.copySnapshot(ctx, srcSnapshot.UUID, lvolName, lvolAlias); err != nil {
+		return "", err
+	}
+
+	if parent != "" {
+		if _, err := r.dstCli.BdevLvolSetParent(lvolAlias, parent); err != nil {
+			return "", errors.Wrapf(err, "failed to set parent %s for rebuilding lvol %s", parent, lvolAlias)
+		}
+	}
+
+	xattrs := []client.Xattr{
+		{
+			Name:  RebuildSource,
+			Value: srcSnapshot.UUID,
+		},
+	}
+	for _, key := range []string{client.UserCreated, client.SnapshotTimestamp} {
+		if value, exists := srcSnapshot.DriverSpecific.Lvol.Xattrs[key]; exists {
+			xattrs = append(xattrs, client.Xattr{Name: key, Value: value})
+		}
+	}
+
+	return r.dstCli.BdevLvolSnapshot(lvolAlias, snapshotName, xattrs)
+}
+
+// copySnapshot exposes the destination lvol, attaches it on the source node, then shallow copies the source snapshot to it.
+//
+// SPDK provides no way to abort a running shallow copy, so the cancellation of ctx does not interrupt the copy.
+// Instead, it waits for the copy to finish before detaching and deleting the destination lvol, then returns the ctx error.
+func (r *Rebuilder) copySnapshot(ctx context.Context, srcSnapshot, lvolName, lvolAlias string) (err error) {
+	nqn := types.GetNQN(lvolName)
+	if err := r.dstCli.StartExposeBdev(nqn, lvolAlias, "", r.dstIP, r.dstPort); err != nil {
+		return errors.Wrapf(err, "failed to expose rebuilding lvol %s", lvolAlias)
+	}
+	defer func() {
+		if stopErr := r.dstCli.StopExposeBdev(nqn); stopErr != nil {
+			r.log.WithError(stopErr).Warnf("Failed to stop exposing rebuilding lvol %s", lvolAlias)
+		}
+	}()
+
+	controllerName := lvolName
+	bdevNameList, err := r.srcCli.BdevNvmeAttachController(controllerName, nqn, r.dstIP, r.dstPort,
+		spdktypes.NvmeTransportTypeTCP, spdktypes.GetNvmeAddressFamily(r.dstIP),
+		types.DefaultCtrlrLossTimeoutSec, types.DefaultReconnectDelaySec, types.DefaultFastIOFailTimeoutSec, types.DefaultMultipath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to attach rebuilding lvol %s on the source node", lvolAlias)
+	}
+	defer func() {
+		if _, detachErr := r.srcCli.BdevNvmeDetachController(controllerName); detachErr != nil {
+			r.log.WithError(detachErr).Warnf("Failed to detach NVMe controller %s on the source node", controllerName)
+		}
+	}()
+	if len(bdevNameList) != 1 {
+		return fmt.Errorf("zero or multiple bdevs %v found after attaching rebuilding lvol %s", bdevNameList, lvolAlias)
+	}
+
+	// The job is not bound to ctx, otherwise its tracking stops on cancellation while SPDK is still writing to the bdev
+	job, err := r.copyManager.Start(context.Background(), srcSnapshot, bdevNameList[0])
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-job.Done():
+		return job.Err()
+	case <-ctx.Done():
+		r.log.Warnf("Waiting for the shallow copy of snapshot %s to finish before cleaning up since the rebuild is canceled", srcSnapshot)
+		if err := job.Wait(); err != nil {
+			r.log.WithError(err).Warnf("Shallow copy of snapshot %s failed after the rebuild is canceled", srcSnapshot)
+		}
+		return ctx.Err()
+	}
+}
+
+// verifySnapshot compares the checksums of the source and the rebuilt snapshot.
+// A registered checksum is reused, so the verification of a snapshot is not repeated after resuming.
+func (r *Rebuilder) verifySnapshot(srcSnapshot, dstSnapshot string) error {
+	srcChecksum, err := getOrRegisterSnapshotChecksum(r.srcCli, srcSnapshot)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get checksum of source snapshot %s", srcSnapshot)
+	}
+	dstChecksum, err := getOrRegisterSnapshotChecksum(r.dstCli, dstSnapshot)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get checksum of rebuilt snapshot %s", dstSnapshot)
+	}
+	if srcChecksum != dstChecksum {
+		return fmt.Errorf("checksum %s of rebuilt snapshot %s mismatches checksum %s of source snapshot %s",
+			dstChecksum, dstSnapshot, srcChecksum, srcSnapshot)
+	}
+	return nil
+}
+
+func getOrRegisterSnapshotChecksum(spdkCli *client.Client, name string) (string, error) {
+	checksum, err := spdkCli.BdevLvolGetSnapshotChecksum(name)
+	if err == nil {
+		return checksum, nil
+	}
+	if !jsonrpc.IsJSONRPCRespErrorNoSuchFile(err) {
+		return "", err
+	}
+	if _, err := spdkCli.BdevLvolRegisterSnapshotChecksum(name); err != nil {
+		return "", err
+	}
+	return spdkCli.BdevLvolGetSnapshotChecksum(name)
+}
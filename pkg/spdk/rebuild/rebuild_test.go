@@ -0,0 +1,434 @@
+package rebuild
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc/jsonrpctest"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/shallowcopy"
+	"github.com/longhorn/go-spdk-helper/pkg/types"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+const (
+	srcLvsName = "lvs"
+	dstLvsName = "lvs-dst"
+)
+
+// fakeNode keeps the lvols, the xattrs and the checksums of a node in memory.
+// The lvols are looked up by UUID or alias, and the other maps are keyed by the lvol UUID.
+type fakeNode struct {
+	sync.Mutex
+
+	lvols  []spdktypes.BdevInfo
+	xattrs map[string]map[string]string
+	// contents is the checksum a snapshot gets on registration
+	contents  map[string]uint64
+	checksums map[string]uint64
+
+	subsystems map[string]bool
+
+	server *jsonrpctest.Server
+}
+
+func newLvol(lvsName, name, baseSnapshot string, snapshot bool) spdktypes.BdevInfo {
+	return spdktypes.BdevInfo{
+		BdevInfoBasic: spdktypes.BdevInfoBasic{
+			Name:        name + "-uuid",
+			Aliases:     []string{spdktypes.GetLvolAlias(lvsName, name)},
+			ProductName: spdktypes.BdevProductNameLvol,
+			BlockSize:   512,
+			NumBlocks:   2048,
+			UUID:        name + "-uuid",
+		},
+		DriverSpecific: &spdktypes.BdevDriverSpecific{
+			Lvol: &spdktypes.BdevDriverSpecificLvol{
+				LvolStoreUUID: lvsName + "-uuid",
+				BaseSnapshot:  baseSnapshot,
+				Snapshot:      snapshot,
+			},
+		},
+	}
+}
+
+func newFakeNode(lvols ...spdktypes.BdevInfo) *fakeNode {
+	n := &fakeNode{
+		lvols:      lvols,
+		xattrs:     map[string]map[string]string{},
+		contents:   map[string]uint64{},
+		checksums:  map[string]uint64{},
+		subsystems: map[string]bool{},
+	}
+	n.server = jsonrpctest.NewServer(map[string]jsonrpctest.Handler{
+		"bdev_get_bdevs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevGetBdevsRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			n.Lock()
+			defer n.Unlock()
+			if req.Name == "" {
+				return n.lvols, nil
+			}
+			lvol := n.findWithoutLock(req.Name)
+			if lvol == nil {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchDevice, Message: "No such device"}
+			}
+			return []spdktypes.BdevInfo{*lvol}, nil
+		},
+		"bdev_lvol_get_xattr": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolGetXattrRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			n.Lock()
+			defer n.Unlock()
+			value, ok := n.xattrs[n.uuidWithoutLock(req.Name)][req.XattrName]
+			if !ok {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchFile, Message: "No such file or directory"}
+			}
+			return value, nil
+		},
+		"bdev_lvol_get_snapshot_checksum": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolGetSnapshotChecksumRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			n.Lock()
+			defer n.Unlock()
+			checksum, ok := n.checksums[n.uuidWithoutLock(req.Name)]
+			if !ok {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchFile, Message: "No such file or directory"}
+			}
+			return spdktypes.BdevLvolSnapshotChecksum{Checksum: checksum}, nil
+		},
+		"bdev_lvol_register_snapshot_checksum": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolRegisterSnapshotChecksumRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			n.Lock()
+			defer n.Unlock()
+			uuid := n.uuidWithoutLock(req.Name)
+			n.checksums[uuid] = n.contents[uuid]
+			return true, nil
+		},
+		"bdev_lvol_get_lvstores": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.LvstoreInfo{{UUID: srcLvsName + "-uuid", Name: srcLvsName, ClusterSize: types.MiB}}, nil
+		},
+		"bdev_lvol_create": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolCreateRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			return req.LvolName + "-uuid", nil
+		},
+		"bdev_lvol_snapshot": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolSnapshotRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			n.Lock()
+			defer n.Unlock()
+			uuid := req.SnapshotName + "-rebuilt"
+			n.xattrs[uuid] = req.Xattrs
+			return uuid, nil
+		},
+		"bdev_nvme_attach_controller": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevNvmeAttachControllerRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			return []string{req.Name + "n1"}, nil
+		},
+		"bdev_lvol_start_shallow_copy": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return spdktypes.ShallowCopy{OperationId: 1}, nil
+		},
+		"bdev_lvol_check_shallow_copy": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return spdktypes.ShallowCopyStatus{State: types.ShallowCopyStateComplete, CopiedClusters: 1, TotalClusters: 1}, nil
+		},
+		"nvmf_get_transports": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.NvmfTransport{{Trtype: spdktypes.NvmeTransportTypeTCP}}, nil
+		},
+		"nvmf_create_subsystem": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.NvmfCreateSubsystemRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			n.Lock()
+			defer n.Unlock()
+			n.subsystems[req.Nqn] = true
+			return true, nil
+		},
+		"nvmf_subsystem_add_ns": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return 1, nil
+		},
+		"nvmf_get_subsystems": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			n.Lock()
+			defer n.Unlock()
+			subsystemList := []spdktypes.NvmfSubsystem{}
+			for nqn := range n.subsystems {
+				subsystemList = append(subsystemList, spdktypes.NvmfSubsystem{Nqn: nqn})
+			}
+			return subsystemList, nil
+		},
+		"nvmf_subsystem_get_listeners": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			return []spdktypes.NvmfSubsystemListener{}, nil
+		},
+		"nvmf_delete_subsystem": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.NvmfDeleteSubsystemRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			n.Lock()
+			defer n.Unlock()
+			delete(n.subsystems, req.Nqn)
+			return true, nil
+		},
+	})
+	return n
+}
+
+func (n *fakeNode) findWithoutLock(name string) *spdktypes.BdevInfo {
+	for i := range n.lvols {
+		if n.lvols[i].UUID == name || n.lvols[i].Aliases[0] == name {
+			return &n.lvols[i]
+		}
+	}
+	return nil
+}
+
+func (n *fakeNode) uuidWithoutLock(name string) string {
+	if lvol := n.findWithoutLock(name); lvol != nil {
+		return lvol.UUID
+	}
+	return name
+}
+
+func newTestClient(server *jsonrpctest.Server) (*client.Client, func()) {
+	conn, closeConn := server.Dial()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return client.NewClientWithConn(ctx, conn), func() {
+		cancel()
+		closeConn()
+	}
+}
+
+func newTestRebuilder(c *C, src, dst *fakeNode) (*Rebuilder, func()) {
+	srcCli, closeSrc := newTestClient(src.server)
+	dstCli, closeDst := newTestClient(dst.server)
+
+	r, err := NewRebuilder(srcCli, dstCli, dstLvsName, "10.0.0.2", "20001")
+	c.Assert(err, IsNil)
+	r.copyManager, err = shallowcopy.NewManager(srcCli, 10*time.Millisecond, 0)
+	c.Assert(err, IsNil)
+
+	return r, func() {
+		closeSrc()
+		closeDst()
+	}
+}
+
+// newSrcNode returns a node with the chain snap1 <- snap2 <- vol, whose snapshot checksums are not registered yet.
+func newSrcNode() *fakeNode {
+	src := newFakeNode(
+		newLvol(srcLvsName, "snap1", "", true),
+		newLvol(srcLvsName, "snap2", "snap1", true),
+		newLvol(srcLvsName, "vol", "snap2", false),
+	)
+	src.contents["snap1-uuid"] = 1111
+	src.contents["snap2-uuid"] = 2222
+	return src
+}
+
+// newDstNode returns a node with snap1 already rebuilt and verified.
+func newDstNode() *fakeNode {
+	snap1 := newLvol(dstLvsName, "snap1", "", true)
+	snap1.Name, snap1.UUID = "snap1-rebuilt", "snap1-rebuilt"
+	dst := newFakeNode(snap1)
+	dst.xattrs["snap1-rebuilt"] = map[string]string{RebuildSource: "snap1-uuid"}
+	dst.checksums["snap1-rebuilt"] = 1111
+	dst.contents["snap2-rebuilt"] = 2222
+	return dst
+}
+
+func (s *TestSuite) TestGetSnapshotChain(c *C) {
+	src := newSrcNode()
+	spdkCli, closeFn := newTestClient(src.server)
+	defer closeFn()
+
+	chain, err := GetSnapshotChain(spdkCli, "lvs/vol")
+	c.Assert(err, IsNil)
+	c.Assert(chain, DeepEquals, []string{"snap1-uuid", "snap2-uuid"})
+
+	chain, err = GetSnapshotChain(spdkCli, "snap2-uuid")
+	c.Assert(err, IsNil)
+	c.Assert(chain, DeepEquals, []string{"snap1-uuid"})
+
+	chain, err = GetSnapshotChain(spdkCli, "lvs/snap1")
+	c.Assert(err, IsNil)
+	c.Assert(chain, HasLen, 0)
+
+	// A broken chain is reported rather than truncated
+	broken := newFakeNode(newLvol(srcLvsName, "vol", "snap0", false))
+	brokenCli, brokenCloseFn := newTestClient(broken.server)
+	defer brokenCloseFn()
+
+	_, err = GetSnapshotChain(brokenCli, "lvs/vol")
+	c.Assert(jsonrpc.IsJSONRPCRespErrorNoSuchDevice(err), Equals, true)
+}
+
+func (s *TestSuite) TestRebuild(c *C) {
+	src := newSrcNode()
+	dst := newDstNode()
+	r, closeFn := newTestRebuilder(c, src, dst)
+	defer closeFn()
+
+	dstSnapshots, err := r.Rebuild(context.Background(), []string{"snap1-uuid", "lvs/snap2"})
+	c.Assert(err, IsNil)
+	c.Assert(dstSnapshots, DeepEquals, []string{"snap1-rebuilt", "snap2-rebuilt"})
+
+	// Only snap2 is copied, and it is relinked to the rebuilt snap1
+	copyReqs := []spdktypes.BdevLvolShallowCopyRequest{}
+	c.Assert(src.server.RequestsOf("bdev_lvol_start_shallow_copy", &copyReqs), IsNil)
+	c.Assert(copyReqs, DeepEquals, []spdktypes.BdevLvolShallowCopyRequest{{SrcLvolName: "snap2-uuid", DstBdevName: "snap2-rebuildingn1"}})
+
+	setParentReqs := []spdktypes.BdevLvolSetParentRequest{}
+	c.Assert(dst.server.RequestsOf("bdev_lvol_set_parent", &setParentReqs), IsNil)
+	c.Assert(setParentReqs, DeepEquals, []spdktypes.BdevLvolSetParentRequest{{LvolName: "lvs-dst/snap2-rebuilding", ParentName: "snap1-rebuilt"}})
+
+	c.Assert(dst.xattrs["snap2-rebuilt"][RebuildSource], Equals, "snap2-uuid")
+
+	// The checksums are registered once, and the registered snap1 checksum of the destination is reused
+	registerReqs := []spdktypes.BdevLvolRegisterSnapshotChecksumRequest{}
+	c.Assert(src.server.RequestsOf("bdev_lvol_register_snapshot_checksum", &registerReqs), IsNil)
+	c.Assert(registerReqs, DeepEquals, []spdktypes.BdevLvolRegisterSnapshotChecksumRequest{{Name: "snap1-uuid"}, {Name: "snap2-uuid"}})
+	registerReqs = []spdktypes.BdevLvolRegisterSnapshotChecksumRequest{}
+	c.Assert(dst.server.RequestsOf("bdev_lvol_register_snapshot_checksum", &registerReqs), IsNil)
+	c.Assert(registerReqs, DeepEquals, []spdktypes.BdevLvolRegisterSnapshotChecksumRequest{{Name: "snap2-rebuilt"}})
+
+	// The exposed lvol and the attached controller are cleaned up
+	c.Assert(dst.subsystems, HasLen, 0)
+	detachReqs := []spdktypes.BdevNvmeDetachControllerRequest{}
+	c.Assert(src.server.RequestsOf("bdev_nvme_detach_controller", &detachReqs), IsNil)
+	c.Assert(detachReqs, DeepEquals, []spdktypes.BdevNvmeDetachControllerRequest{{Name: "snap2-rebuilding"}})
+}
+
+func (s *TestSuite) TestRebuildVerifyFailure(c *C) {
+	src := newSrcNode()
+	dst := newDstNode()
+	dst.contents["snap2-rebuilt"] = 3333
+	r, closeFn := newTestRebuilder(c, src, dst)
+	defer closeFn()
+
+	_, err := r.Rebuild(context.Background(), []string{"snap1-uuid", "snap2-uuid"})
+	c.Assert(err, ErrorMatches, "checksum 3333 of rebuilt snapshot snap2-rebuilt mismatches checksum 2222 of source snapshot snap2-uuid")
+
+	// Only the missing checksum is registered, other errors are returned as they are
+	src = newSrcNode()
+	src.server.Handle("bdev_lvol_get_snapshot_checksum", func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+		return nil, &jsonrpc.ResponseError{Code: -5, Message: "Input/output error"}
+	})
+	r, closeFn = newTestRebuilder(c, src, newDstNode())
+	defer closeFn()
+
+	_, err = r.Rebuild(context.Background(), []string{"snap1-uuid"})
+	c.Assert(err, ErrorMatches, "failed to get checksum of source snapshot snap1-uuid: .*Input/output error.*")
+	registerReqs := []spdktypes.BdevLvolRegisterSnapshotChecksumRequest{}
+	c.Assert(src.server.RequestsOf("bdev_lvol_register_snapshot_checksum", &registerReqs), IsNil)
+	c.Assert(registerReqs, HasLen, 0)
+}
+
+func (s *TestSuite) TestRebuildCopyFailure(c *C) {
+	src := newSrcNode()
+	src.server.Handle("bdev_lvol_check_shallow_copy", func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+		return spdktypes.ShallowCopyStatus{State: types.ShallowCopyStateError, TotalClusters: 1, Error: "Input/output error"}, nil
+	})
+	dst := newDstNode()
+	r, closeFn := newTestRebuilder(c, src, dst)
+	defer closeFn()
+
+	_, err := r.Rebuild(context.Background(), []string{"snap1-uuid", "snap2-uuid"})
+	c.Assert(err, ErrorMatches, "failed to rebuild snapshot snap2-uuid: shallow copy operation 1 .* failed: Input/output error")
+
+	// Everything set up for the copy is torn down, and no snapshot is taken
+	c.Assert(dst.subsystems, HasLen, 0)
+	c.Assert(src.server.Methods()[len(src.server.Methods())-1], Equals, "bdev_nvme_detach_controller")
+	deleteReqs := []spdktypes.BdevLvolDeleteRequest{}
+	c.Assert(dst.server.RequestsOf("bdev_lvol_delete", &deleteReqs), IsNil)
+	c.Assert(deleteReqs, DeepEquals, []spdktypes.BdevLvolDeleteRequest{{Name: "lvs-dst/snap2-rebuilding"}, {Name: "lvs-dst/snap2-rebuilding"}})
+	snapshotReqs := []spdktypes.BdevLvolSnapshotRequest{}
+	c.Assert(dst.server.RequestsOf("bdev_lvol_snapshot", &snapshotReqs), IsNil)
+	c.Assert(snapshotReqs, HasLen, 0)
+}
+
+func (s *TestSuite) TestRebuildCanceled(c *C) {
+	lock := sync.Mutex{}
+	copied := false
+	src := newSrcNode()
+	src.server.Handle("bdev_lvol_check_shallow_copy", func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+		lock.Lock()
+		defer lock.Unlock()
+		if !copied {
+			return spdktypes.ShallowCopyStatus{State: types.ShallowCopyStateInProgress, TotalClusters: 1}, nil
+		}
+		return spdktypes.ShallowCopyStatus{State: types.ShallowCopyStateComplete, CopiedClusters: 1, TotalClusters: 1}, nil
+	})
+	dst := newDstNode()
+	r, closeFn := newTestRebuilder(c, src, dst)
+	defer closeFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		_, err := r.Rebuild(ctx, []string{"snap1-uuid", "snap2-uuid"})
+		errCh <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	// The destination lvol is kept attached while SPDK is still copying to it
+	select {
+	case err := <-errCh:
+		c.Fatalf("rebuild returned before the copy finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	detachReqs := []spdktypes.BdevNvmeDetachControllerRequest{}
+	c.Assert(src.server.RequestsOf("bdev_nvme_detach_controller", &detachReqs), IsNil)
+	c.Assert(detachReqs, HasLen, 0)
+
+	lock.Lock()
+	copied = true
+	lock.Unlock()
+
+	select {
+	case err := <-errCh:
+		c.Assert(errors.Cause(err), Equals, context.Canceled)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the canceled rebuild")
+	}
+	c.Assert(src.server.RequestsOf("bdev_nvme_detach_controller", &detachReqs), IsNil)
+	c.Assert(detachReqs, HasLen, 1)
+	c.Assert(dst.subsystems, HasLen, 0)
+	snapshotReqs := []spdktypes.BdevLvolSnapshotRequest{}
+	c.Assert(dst.server.RequestsOf("bdev_lvol_snapshot", &snapshotReqs), IsNil)
+	c.Assert(snapshotReqs, HasLen, 0)
+}
@@ -0,0 +1,290 @@
+package scrub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/metadata"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+const (
+	// StateLvolName is the name of the lvol keeping the scrub state of the snapshots in the same lvstore.
+	// Snapshots are read-only, so their xattrs cannot be updated after creation. Instead, the expected checksum
+	// and the last scrub time of each snapshot are stored in the xattrs of this lvol.
+	//
+	// The first scrub of a lvstore creates it as a regular, hence writable, thin provisioned lvol of 1 MiB.
+	// Nothing writes its data, so it never allocates any cluster. It shows up among the lvols of the lvstore,
+	// and deleting it only makes the next scrub register the checksums again.
+	StateLvolName = "scrub-state"
+	// StateNamespace is the namespace of the xattrs of the state lvol.
+	// Each snapshot has its own xattr, e.g. "scrub.<SNAPSHOT UUID>", so the size of a xattr does not grow with the
+	// snapshot count. The xattrs of the deleted snapshots are cleared by every scrub.
+	StateNamespace = "scrub"
+
+	DefaultInterval = 10 * time.Second
+
+	stateLvolSizeInMib = 1
+)
+
+type ResultState string
+
+const (
+	// ResultStateRegistered means the snapshot had no expected checksum, and the computed one is stored.
+	ResultStateRegistered = ResultState("registered")
+	// ResultStateVerified means the computed checksum matches the expected one.
+	ResultStateVerified = ResultState("verified")
+	// ResultStateMismatched means the computed checksum differs from the expected one.
+	// The expected checksum is kept so that later runs keep reporting the mismatch.
+	ResultStateMismatched = ResultState("mismatched")
+	// ResultStateFailed means the checksum cannot be computed or stored.
+	ResultStateFailed = ResultState("failed")
+)
+
+// Result is the outcome of scrubbing one snapshot. It is also the event sent during the scrub.
+type Result struct {
+	Name             string      `json:"name"`
+	UUID             string      `json:"uuid"`
+	State            ResultState `json:"state"`
+	ExpectedChecksum string      `json:"expected_checksum,omitempty"`
+	ActualChecksum   string      `json:"actual_checksum,omitempty"`
+	Timestamp        time.Time   `json:"timestamp"`
+	Error            string      `json:"error,omitempty"`
+}
+
+// snapshotState is the scrub state of a snapshot stored in the state lvol.
+type snapshotState struct {
+	// Checksum is the expected checksum
+	Checksum string `json:"checksum"`
+	// Timestamp is the last time the checksum is registered or verified
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type Report struct {
+	LvsName    string    `json:"lvs_name"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	Registered int `json:"registered"`
+	Verified   int `json:"verified"`
+	Mismatched int `json:"mismatched"`
+	Failed     int `json:"failed"`
+	// Pruned is the count of the states of deleted snapshots cleared by this scrub
+	Pruned int `json:"pruned"`
+
+	Results []Result `json:"results"`
+}
+
+// Scrubber computes the checksums of all snapshots in a lvstore and compares them with the ones stored by previous runs.
+type Scrubber struct {
+	spdkCli *client.Client
+	store   *metadata.Store
+
+	lvsName  string
+	interval time.Duration
+
+	log logrus.FieldLogger
+}
+
+// NewScrubber creates a scrubber.
+//
+//	"lvsName": Required. Name of the lvstore to scrub.
+//
+//	"interval": Optional. The pause between two snapshots to throttle the checksum computation. DefaultInterval by default.
+func NewScrubber(spdkCli *client.Client, lvsName string, interval time.Duration) (*Scrubber, error) {
+	if spdkCli == nil {
+		return nil, fmt.Errorf("empty SPDK client for scrubber creation")
+	}
+	if lvsName == "" {
+		return nil, fmt.Errorf("empty lvstore name for scrubber creation")
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	store, err := metadata.NewStore(spdkCli, StateNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scrubber{
+		spdkCli: spdkCli,
+		store:   store,
+
+		lvsName:  lvsName,
+		interval: interval,
+
+		log: logrus.WithField("lvsName", lvsName),
+	}, nil
+}
+
+// Scrub walks all snapshots in the lvstore once. The state lvol StateLvolName is created in the lvstore if it does not exist,
+// and the states of the snapshots no longer in the lvstore are cleared from it.
+// The result of each snapshot is sent to eventCh if it is not nil, so the caller must keep receiving from it.
+// The failure of a single snapshot does not stop the scrub. An error is returned only when the scrub cannot go on.
+func (s *Scrubber) Scrub(ctx context.Context, eventCh chan<- Result) (*Report, error) {
+	report := &Report{
+		LvsName:   s.lvsName,
+		StartedAt: time.Now(),
+		Results:   []Result{},
+	}
+
+	stateLvol, err := s.ensureStateLvol()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotList, err := s.spdkCli.BdevLvolGetWithFilter("", 0, func(b *spdktypes.BdevInfo) bool {
+		return b.DriverSpecific.Lvol.Snapshot && len(b.Aliases) > 0 &&
+			spdktypes.GetLvsNameFromAlias(b.Aliases[0]) == s.lvsName
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list snapshots of lvstore %s", s.lvsName)
+	}
+	defer func() {
+		report.FinishedAt = time.Now()
+	}()
+
+	pruned, err := s.pruneStates(stateLvol, snapshotList)
+	if err != nil {
+		s.log.WithError(err).Warn("Failed to prune the scrub states of deleted snapshots")
+	}
+	report.Pruned = pruned
+
+	for idx, snapshot := range snapshotList {
+		if idx > 0 {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			case <-time.After(s.interval):
+			}
+		}
+
+		result := s.scrubSnapshot(stateLvol, snapshot.Aliases[0], snapshot.UUID)
+		switch result.State {
+		case ResultStateRegistered:
+			report.Registered++
+		case ResultStateVerified:
+			report.Verified++
+		case ResultStateMismatched:
+			report.Mismatched++
+			s.log.Errorf("Found checksum mismatch of snapshot %s: expected %s, actual %s", result.Name, result.ExpectedChecksum, result.ActualChecksum)
+		case ResultStateFailed:
+			report.Failed++
+			s.log.Warnf("Failed to scrub snapshot %s: %v", result.Name, result.Error)
+		}
+		report.Results = append(report.Results, result)
+
+		if eventCh != nil {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			case eventCh <- result:
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// ensureStateLvol returns the UUID of the state lvol, creating it if it does not exist.
+func (s *Scrubber) ensureStateLvol() (string, error) {
+	alias := spdktypes.GetLvolAlias(s.lvsName, StateLvolName)
+
+	lvol, err := s.spdkCli.BdevLvolGetByName(alias, 0)
+	if err == nil {
+		return lvol.UUID, nil
+	}
+	if !jsonrpc.IsJSONRPCRespErrorNoSuchDevice(err) {
+		return "", errors.Wrapf(err, "failed to get scrub state lvol %s", alias)
+	}
+
+	s.log.Infof("Creating scrub state lvol %s to keep the expected checksums of the snapshots", alias)
+	uuid, err := s.spdkCli.BdevLvolCreate(s.lvsName, "", StateLvolName, stateLvolSizeInMib, "", true)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create scrub state lvol %s", alias)
+	}
+	return uuid, nil
+}
+
+// pruneStates clears the states of the snapshots not in snapshotList, and returns the count of the cleared states.
+func (s *Scrubber) pruneStates(stateLvol string, snapshotList []spdktypes.BdevInfo) (int, error) {
+	states, err := s.store.GetAll(stateLvol)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get scrub states")
+	}
+
+	existing := map[string]struct{}{}
+	for _, snapshot := range snapshotList {
+		existing[snapshot.UUID] = struct{}{}
+	}
+
+	pruned := 0
+	for uuid := range states {
+		if _, ok := existing[uuid]; ok {
+			continue
+		}
+		if err := s.store.Delete(stateLvol, uuid); err != nil {
+			return pruned, errors.Wrapf(err, "failed to clear scrub state of deleted snapshot %s", uuid)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+func (s *Scrubber) scrubSnapshot(stateLvol, name, uuid string) Result {
+	result := Result{
+		Name: name,
+		UUID: uuid,
+	}
+
+	state := snapshotState{}
+	if _, err := s.store.Get(stateLvol, uuid, &state); err != nil {
+		return failed(result, errors.Wrap(err, "failed to get expected checksum"))
+	}
+	expected := state.Checksum
+	result.ExpectedChecksum = expected
+
+	if _, err := s.spdkCli.BdevLvolRegisterSnapshotChecksum(uuid); err != nil {
+		return failed(result, errors.Wrap(err, "failed to register checksum"))
+	}
+	actual, err := s.spdkCli.BdevLvolGetSnapshotChecksum(uuid)
+	if err != nil {
+		return failed(result, errors.Wrap(err, "failed to get checksum"))
+	}
+	result.ActualChecksum = actual
+	result.Timestamp = time.Now()
+
+	switch {
+	case expected == "":
+		result.ExpectedChecksum = actual
+		result.State = ResultStateRegistered
+	case expected == actual:
+		result.State = ResultStateVerified
+	default:
+		result.State = ResultStateMismatched
+		return result
+	}
+
+	state = snapshotState{
+		Checksum:  result.ExpectedChecksum,
+		Timestamp: result.Timestamp.UTC(),
+	}
+	if err := s.store.Set(stateLvol, uuid, state); err != nil {
+		return failed(result, errors.Wrap(err, "failed to store scrub state"))
+	}
+	return result
+}
+
+func failed(result Result, err error) Result {
+	result.State = ResultStateFailed
+	result.Error = err.Error()
+	return result
+}
@@ -0,0 +1,361 @@
+package scrub_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc/jsonrpctest"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/scrub"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+// fakeLvstore keeps the lvols of the lvstore "lvs" in memory.
+// Like SPDK, it refuses to set the xattrs of a snapshot, and reports the xattrs of the lvols in bdev_get_bdevs.
+type fakeLvstore struct {
+	sync.Mutex
+
+	lvols  []spdktypes.BdevInfo
+	xattrs map[string]map[string]string
+	// contents is the checksum a snapshot gets on registration
+	contents  map[string]uint64
+	checksums map[string]uint64
+
+	server *jsonrpctest.Server
+}
+
+func newLvol(name string, snapshot bool) spdktypes.BdevInfo {
+	return spdktypes.BdevInfo{
+		BdevInfoBasic: spdktypes.BdevInfoBasic{
+			Name:        name + "-uuid",
+			Aliases:     []string{spdktypes.GetLvolAlias("lvs", name)},
+			ProductName: spdktypes.BdevProductNameLvol,
+			UUID:        name + "-uuid",
+		},
+		DriverSpecific: &spdktypes.BdevDriverSpecific{
+			Lvol: &spdktypes.BdevDriverSpecificLvol{
+				Snapshot: snapshot,
+			},
+		},
+	}
+}
+
+func newFakeLvstore(lvols ...spdktypes.BdevInfo) *fakeLvstore {
+	l := &fakeLvstore{
+		lvols:     lvols,
+		xattrs:    map[string]map[string]string{},
+		contents:  map[string]uint64{},
+		checksums: map[string]uint64{},
+	}
+	l.server = jsonrpctest.NewServer(map[string]jsonrpctest.Handler{
+		"bdev_get_bdevs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevGetBdevsRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			l.Lock()
+			defer l.Unlock()
+			if req.Name == "" {
+				lvols := []spdktypes.BdevInfo{}
+				for i := range l.lvols {
+					lvols = append(lvols, l.reportWithoutLock(&l.lvols[i]))
+				}
+				return lvols, nil
+			}
+			lvol := l.findWithoutLock(req.Name)
+			if lvol == nil {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchDevice, Message: "No such device"}
+			}
+			return []spdktypes.BdevInfo{l.reportWithoutLock(lvol)}, nil
+		},
+		"bdev_lvol_create": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolCreateRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			l.Lock()
+			defer l.Unlock()
+			lvol := newLvol(req.LvolName, false)
+			lvol.DriverSpecific.Lvol.ThinProvision = req.ThinProvision
+			l.lvols = append(l.lvols, lvol)
+			return lvol.UUID, nil
+		},
+		"bdev_lvol_get_xattr": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolGetXattrRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			l.Lock()
+			defer l.Unlock()
+			value, ok := l.xattrs[req.Name][req.XattrName]
+			if !ok {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchFile, Message: "No such file or directory"}
+			}
+			return value, nil
+		},
+		"bdev_lvol_set_xattr": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolSetXattrRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			l.Lock()
+			defer l.Unlock()
+			if lvol := l.findWithoutLock(req.Name); lvol == nil || lvol.DriverSpecific.Lvol.Snapshot {
+				return nil, &jsonrpc.ResponseError{Code: -1, Message: "Operation not permitted"}
+			}
+			if l.xattrs[req.Name] == nil {
+				l.xattrs[req.Name] = map[string]string{}
+			}
+			l.xattrs[req.Name][req.XattrName] = req.XattrValue
+			return true, nil
+		},
+		"bdev_lvol_register_snapshot_checksum": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolRegisterSnapshotChecksumRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			l.Lock()
+			defer l.Unlock()
+			l.checksums[req.Name] = l.contents[req.Name]
+			return true, nil
+		},
+		"bdev_lvol_get_snapshot_checksum": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolGetSnapshotChecksumRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			l.Lock()
+			defer l.Unlock()
+			checksum, ok := l.checksums[req.Name]
+			if !ok {
+				return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchFile, Message: "No such file or directory"}
+			}
+			return spdktypes.BdevLvolSnapshotChecksum{Checksum: checksum}, nil
+		},
+	})
+	return l
+}
+
+func (l *fakeLvstore) findWithoutLock(name string) *spdktypes.BdevInfo {
+	for i := range l.lvols {
+		if l.lvols[i].UUID == name || l.lvols[i].Aliases[0] == name {
+			return &l.lvols[i]
+		}
+	}
+	return nil
+}
+
+// reportWithoutLock returns a copy of the lvol with its xattrs.
+func (l *fakeLvstore) reportWithoutLock(lvol *spdktypes.BdevInfo) spdktypes.BdevInfo {
+	lvolInfo := *lvol
+	lvolSpecific := *lvol.DriverSpecific.Lvol
+	lvolSpecific.Xattrs = map[string]string{}
+	for name, value := range l.xattrs[lvol.UUID] {
+		lvolSpecific.Xattrs[name] = value
+	}
+	lvolInfo.DriverSpecific = &spdktypes.BdevDriverSpecific{Lvol: &lvolSpecific}
+	return lvolInfo
+}
+
+func (l *fakeLvstore) deleteLvol(uuid string) {
+	l.Lock()
+	defer l.Unlock()
+	for i := range l.lvols {
+		if l.lvols[i].UUID == uuid {
+			l.lvols = append(l.lvols[:i], l.lvols[i+1:]...)
+			return
+		}
+	}
+}
+
+func (l *fakeLvstore) getXattrs(uuid string) map[string]string {
+	l.Lock()
+	defer l.Unlock()
+	xattrs := map[string]string{}
+	for name, value := range l.xattrs[uuid] {
+		xattrs[name] = value
+	}
+	return xattrs
+}
+
+func (l *fakeLvstore) setContent(uuid string, checksum uint64) {
+	l.Lock()
+	defer l.Unlock()
+	l.contents[uuid] = checksum
+}
+
+// snapshotState is the scrub state of a snapshot in the state lvol.
+type snapshotState struct {
+	Checksum  string    `json:"checksum"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func newTestScrubber(c *C, server *jsonrpctest.Server) (*scrub.Scrubber, func()) {
+	conn, closeConn := server.Dial()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	scrubber, err := scrub.NewScrubber(client.NewClientWithConn(ctx, conn), "lvs", time.Millisecond)
+	c.Assert(err, IsNil)
+	return scrubber, func() {
+		cancel()
+		closeConn()
+	}
+}
+
+func getStates(report *scrub.Report) map[string]scrub.ResultState {
+	states := map[string]scrub.ResultState{}
+	for _, result := range report.Results {
+		states[result.UUID] = result.State
+	}
+	return states
+}
+
+func (s *TestSuite) TestScrub(c *C) {
+	lvstore := newFakeLvstore(newLvol("snap1", true), newLvol("snap2", true), newLvol("vol", false))
+	lvstore.setContent("snap1-uuid", 1111)
+	lvstore.setContent("snap2-uuid", 2222)
+	scrubber, closeFn := newTestScrubber(c, lvstore.server)
+	defer closeFn()
+
+	// The first scrub creates the state lvol and registers the checksums
+	eventCh := make(chan scrub.Result, 2)
+	report, err := scrubber.Scrub(context.Background(), eventCh)
+	c.Assert(err, IsNil)
+	c.Assert(report.Registered, Equals, 2)
+	c.Assert(getStates(report), DeepEquals, map[string]scrub.ResultState{
+		"snap1-uuid": scrub.ResultStateRegistered,
+		"snap2-uuid": scrub.ResultStateRegistered,
+	})
+	c.Assert(eventCh, HasLen, 2)
+
+	createReqs := []spdktypes.BdevLvolCreateRequest{}
+	c.Assert(lvstore.server.RequestsOf("bdev_lvol_create", &createReqs), IsNil)
+	c.Assert(createReqs, HasLen, 1)
+	c.Assert(createReqs[0].LvolName, Equals, scrub.StateLvolName)
+	c.Assert(createReqs[0].ThinProvision, Equals, true)
+
+	state := snapshotState{}
+	c.Assert(json.Unmarshal([]byte(lvstore.xattrs["scrub-state-uuid"]["scrub.snap1-uuid"]), &state), IsNil)
+	c.Assert(state.Checksum, Equals, "1111")
+	c.Assert(state.Timestamp.IsZero(), Equals, false)
+
+	// The next scrub reuses the state lvol and verifies the checksums
+	report, err = scrubber.Scrub(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Verified, Equals, 2)
+	c.Assert(lvstore.server.RequestsOf("bdev_lvol_create", &createReqs), IsNil)
+	c.Assert(createReqs, HasLen, 1)
+
+	verifiedState := snapshotState{}
+	c.Assert(json.Unmarshal([]byte(lvstore.xattrs["scrub-state-uuid"]["scrub.snap1-uuid"]), &verifiedState), IsNil)
+	c.Assert(verifiedState.Checksum, Equals, "1111")
+	c.Assert(verifiedState.Timestamp.Before(state.Timestamp), Equals, false)
+
+	// A mismatch keeps the expected checksum, so that it is reported again by the later scrubs
+	lvstore.setContent("snap2-uuid", 3333)
+	for i := 0; i < 2; i++ {
+		report, err = scrubber.Scrub(context.Background(), nil)
+		c.Assert(err, IsNil)
+		c.Assert(report.Verified, Equals, 1)
+		c.Assert(report.Mismatched, Equals, 1)
+		for _, result := range report.Results {
+			if result.UUID == "snap2-uuid" {
+				c.Assert(result.State, Equals, scrub.ResultStateMismatched)
+				c.Assert(result.ExpectedChecksum, Equals, "2222")
+				c.Assert(result.ActualChecksum, Equals, "3333")
+			}
+		}
+	}
+	c.Assert(lvstore.xattrs["scrub-state-uuid"]["scrub.snap2-uuid"], Matches, `\{"checksum":"2222",.*`)
+}
+
+func (s *TestSuite) TestScrubManySnapshots(c *C) {
+	// More snapshots than a single xattr could index
+	snapshotCount := 120
+	lvols := []spdktypes.BdevInfo{}
+	for i := 0; i < snapshotCount; i++ {
+		lvols = append(lvols, newLvol(fmt.Sprintf("snap%d", i), true))
+	}
+	lvstore := newFakeLvstore(lvols...)
+	for i := 0; i < snapshotCount; i++ {
+		lvstore.setContent(fmt.Sprintf("snap%d-uuid", i), uint64(i))
+	}
+	scrubber, closeFn := newTestScrubber(c, lvstore.server)
+	defer closeFn()
+
+	report, err := scrubber.Scrub(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Registered, Equals, snapshotCount)
+	c.Assert(report.Pruned, Equals, 0)
+
+	// Each snapshot has its own small xattr, so nothing grows with the snapshot count
+	xattrs := lvstore.getXattrs("scrub-state-uuid")
+	c.Assert(xattrs, HasLen, snapshotCount)
+	for name, value := range xattrs {
+		c.Assert(name, Matches, `scrub\.snap[0-9]+-uuid`)
+		c.Assert(len(value) < 128, Equals, true, Commentf("xattr %s is %q", name, value))
+	}
+
+	// The states of the deleted snapshots are cleared by the next scrub only
+	for i := 20; i < snapshotCount; i++ {
+		lvstore.deleteLvol(fmt.Sprintf("snap%d-uuid", i))
+	}
+	report, err = scrubber.Scrub(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Verified, Equals, 20)
+	c.Assert(report.Pruned, Equals, snapshotCount-20)
+
+	xattrs = lvstore.getXattrs("scrub-state-uuid")
+	for i := 0; i < snapshotCount; i++ {
+		value := xattrs[fmt.Sprintf("scrub.snap%d-uuid", i)]
+		if i < 20 {
+			c.Assert(value, Not(Equals), "")
+		} else {
+			c.Assert(value, Equals, "")
+		}
+	}
+
+	// A cleared state is not pruned again, and a snapshot recreated with the same UUID is registered again
+	lvstore.Lock()
+	lvstore.lvols = append(lvstore.lvols, newLvol("snap119", true))
+	lvstore.Unlock()
+	report, err = scrubber.Scrub(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Pruned, Equals, 0)
+	c.Assert(report.Verified, Equals, 20)
+	c.Assert(report.Registered, Equals, 1)
+}
+
+func (s *TestSuite) TestScrubFailure(c *C) {
+	lvstore := newFakeLvstore(newLvol("snap1", true), newLvol(scrub.StateLvolName, false))
+	lvstore.setContent("snap1-uuid", 1111)
+	lvstore.server.Handle("bdev_lvol_get_xattr", func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+		return nil, &jsonrpc.ResponseError{Code: -5, Message: "Input/output error"}
+	})
+	scrubber, closeFn := newTestScrubber(c, lvstore.server)
+	defer closeFn()
+
+	// An unreadable state is not mistaken for a first scrub
+	report, err := scrubber.Scrub(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Failed, Equals, 1)
+	c.Assert(report.Results[0].Error, Matches, "failed to get expected checksum: .*Input/output error.*")
+
+	registerReqs := []spdktypes.BdevLvolRegisterSnapshotChecksumRequest{}
+	c.Assert(lvstore.server.RequestsOf("bdev_lvol_register_snapshot_checksum", &registerReqs), IsNil)
+	c.Assert(registerReqs, HasLen, 0)
+}
@@ -0,0 +1,239 @@
+package shallowcopy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type JobState string
+
+const (
+	JobStateQueued     = JobState("queued")
+	JobStateInProgress = JobState("in progress")
+	JobStateComplete   = JobState("complete")
+	JobStateError      = JobState("error")
+	JobStateCanceled   = JobState("canceled")
+)
+
+const progressChanSize = 16
+
+var ErrJobCanceled = fmt.Errorf("shallow copy job canceled")
+
+// CopyError is returned when SPDK reports the shallow copy in the error state.
+type CopyError struct {
+	OperationID uint32
+	SrcLvolName string
+	DstBdevName string
+	Message     string
+}
+
+func (e *CopyError) Error() string {
+	return fmt.Sprintf("shallow copy operation %d from %s to %s failed: %s", e.OperationID, e.SrcLvolName, e.DstBdevName, e.Message)
+}
+
+func IsCopyError(err error) bool {
+	var copyErr *CopyError
+	return errors.As(err, &copyErr)
+}
+
+// Progress is a snapshot of a shallow copy job.
+type Progress struct {
+	JobID          uint64   `json:"job_id"`
+	SrcLvolName    string   `json:"src_lvol_name"`
+	DstBdevName    string   `json:"dst_bdev_name"`
+	LvsUUID        string   `json:"lvs_uuid"`
+	OperationID    uint32   `json:"operation_id"`
+	State          JobState `json:"state"`
+	CopiedClusters uint64   `json:"copied_clusters"`
+	TotalClusters  uint64   `json:"total_clusters"`
+	ClusterSize    uint64   `json:"cluster_size"`
+
+	// BytesPerSecond is the average throughput since SPDK started the copy.
+	BytesPerSecond uint64 `json:"bytes_per_second"`
+	// ETA is zero until there is enough progress to estimate the remaining time.
+	ETA time.Duration `json:"eta"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Percentage returns the copied percentage in the range [0, 100].
+func (p Progress) Percentage() float64 {
+	if p.TotalClusters == 0 {
+		if p.State == JobStateComplete {
+			return 100
+		}
+		return 0
+	}
+	return float64(p.CopiedClusters) * 100 / float64(p.TotalClusters)
+}
+
+// Job tracks one shallow copy started by the Manager.
+type Job struct {
+	sync.RWMutex
+
+	progress Progress
+	err      error
+
+	startedAt     time.Time
+	startedCopied uint64
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	updateCh chan Progress
+	doneCh   chan struct{}
+}
+
+func newJob(ctx context.Context, id uint64, srcLvolName, dstBdevName, lvsUUID string, clusterSize uint64) *Job {
+	jobCtx, cancel := context.WithCancel(ctx)
+	return &Job{
+		progress: Progress{
+			JobID:       id,
+			SrcLvolName: srcLvolName,
+			DstBdevName: dstBdevName,
+			LvsUUID:     lvsUUID,
+			State:       JobStateQueued,
+			ClusterSize: clusterSize,
+		},
+
+		ctx:      jobCtx,
+		cancel:   cancel,
+		updateCh: make(chan Progress, progressChanSize),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// ID returns the job ID assigned by the Manager.
+func (j *Job) ID() uint64 {
+	return j.progress.JobID
+}
+
+// Progress returns the channel on which progress events are delivered.
+// Intermediate events are dropped rather than blocking the job if the receiver falls behind,
+// but the final event is always delivered before the channel is closed once the job finishes.
+func (j *Job) Progress() <-chan Progress {
+	return j.updateCh
+}
+
+// Status returns the latest progress of the job.
+func (j *Job) Status() Progress {
+	j.RLock()
+	defer j.RUnlock()
+	return j.progress
+}
+
+// Done is closed when the job finishes.
+func (j *Job) Done() <-chan struct{} {
+	return j.doneCh
+}
+
+// Wait blocks until the job finishes and returns its error.
+func (j *Job) Wait() error {
+	<-j.doneCh
+	return j.Err()
+}
+
+// Err returns the error of a finished job.
+func (j *Job) Err() error {
+	j.RLock()
+	defer j.RUnlock()
+	return j.err
+}
+
+// Cancel stops the job. A queued job is never started.
+// SPDK provides no way to abort a running shallow copy, so for a running job this only stops the tracking,
+// and the copy keeps going in the target until it finishes.
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+func (j *Job) setOperationID(operationID uint32) {
+	j.Lock()
+	defer j.Unlock()
+	j.progress.OperationID = operationID
+	j.progress.State = JobStateInProgress
+	j.startedAt = time.Now()
+	j.publish()
+}
+
+func (j *Job) update(state JobState, copiedClusters, totalClusters uint64, now time.Time) {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.progress.State == JobStateInProgress && j.progress.TotalClusters == 0 {
+		// The first poll tells how much had been copied before the tracking started.
+		j.startedCopied = copiedClusters
+	}
+
+	j.progress.State = state
+	j.progress.CopiedClusters = copiedClusters
+	j.progress.TotalClusters = totalClusters
+	j.progress.BytesPerSecond, j.progress.ETA = estimate(j.startedCopied, copiedClusters, totalClusters, j.progress.ClusterSize, now.Sub(j.startedAt))
+	j.publish()
+}
+
+func (j *Job) finish(state JobState, err error) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.progress.State = state
+	if err != nil {
+		j.progress.Error = err.Error()
+	}
+	if state == JobStateComplete {
+		j.progress.ETA = 0
+	}
+	j.err = err
+	j.publishFinal()
+
+	close(j.updateCh)
+	close(j.doneCh)
+	j.cancel()
+}
+
+// publish must be called with the job lock held.
+func (j *Job) publish() {
+	select {
+	case j.updateCh <- j.progress:
+	default:
+	}
+}
+
+// publishFinal makes room for the final event by dropping the oldest pending one if the channel is full.
+// It must be called with the job lock held.
+func (j *Job) publishFinal() {
+	for {
+		select {
+		case j.updateCh <- j.progress:
+			return
+		default:
+		}
+		select {
+		case <-j.updateCh:
+		default:
+		}
+	}
+}
+
+// estimate returns the average throughput in bytes per second and the estimated remaining time.
+func estimate(startedCopied, copiedClusters, totalClusters, clusterSize uint64, elapsed time.Duration) (bytesPerSecond uint64, eta time.Duration) {
+	if elapsed <= 0 || copiedClusters <= startedCopied {
+		return 0, 0
+	}
+
+	copiedBytes := float64((copiedClusters - startedCopied) * clusterSize)
+	rate := copiedBytes / elapsed.Seconds()
+	if rate <= 0 {
+		return 0, 0
+	}
+
+	if totalClusters > copiedClusters {
+		remainingBytes := float64((totalClusters - copiedClusters) * clusterSize)
+		eta = time.Duration(remainingBytes / rate * float64(time.Second))
+	}
+
+	return uint64(rate), eta
+}
@@ -0,0 +1,42 @@
+package shallowcopy
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/types"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+func (s *TestSuite) TestEstimate(c *C) {
+	bytesPerSecond, eta := estimate(0, 0, 100, types.MiB, time.Second)
+	c.Assert(bytesPerSecond, Equals, uint64(0))
+	c.Assert(eta, Equals, time.Duration(0))
+
+	// 10 clusters of 1MiB copied in 2 seconds, 40 clusters left
+	bytesPerSecond, eta = estimate(0, 10, 50, types.MiB, 2*time.Second)
+	c.Assert(bytesPerSecond, Equals, uint64(5*types.MiB))
+	c.Assert(eta, Equals, 8*time.Second)
+
+	// Clusters copied before the tracking started are not counted
+	bytesPerSecond, eta = estimate(20, 30, 50, types.MiB, 2*time.Second)
+	c.Assert(bytesPerSecond, Equals, uint64(5*types.MiB))
+	c.Assert(eta, Equals, 4*time.Second)
+
+	bytesPerSecond, eta = estimate(0, 50, 50, types.MiB, 10*time.Second)
+	c.Assert(bytesPerSecond, Equals, uint64(5*types.MiB))
+	c.Assert(eta, Equals, time.Duration(0))
+}
+
+func (s *TestSuite) TestPercentage(c *C) {
+	c.Assert(Progress{CopiedClusters: 25, TotalClusters: 100}.Percentage(), Equals, float64(25))
+	c.Assert(Progress{State: JobStateInProgress}.Percentage(), Equals, float64(0))
+	c.Assert(Progress{State: JobStateComplete}.Percentage(), Equals, float64(100))
+}
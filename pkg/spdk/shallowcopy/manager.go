@@ -0,0 +1,249 @@
+package shallowcopy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/types"
+)
+
+const (
+	DefaultPollInterval            = 1 * time.Second
+	DefaultMaxConcurrentPerLvstore = 1
+)
+
+// Manager starts SPDK shallow copies and tracks them until they finish.
+type Manager struct {
+	sync.RWMutex
+
+	spdkCli *client.Client
+
+	pollInterval            time.Duration
+	maxConcurrentPerLvstore int
+
+	jobCounter uint64
+	jobs       map[uint64]*Job
+	lvsSlots   map[string]*lvstoreSlots
+}
+
+// lvstoreSlots limits the concurrent copies of a source lvstore.
+// It is removed from the Manager once no job of the lvstore is queued or running.
+type lvstoreSlots struct {
+	slots chan struct{}
+	refs  int
+}
+
+// NewManager creates a shallow copy job manager.
+//
+//	"pollInterval": Optional. How often BdevLvolCheckShallowCopy is called for a running job. DefaultPollInterval by default.
+//
+//	"maxConcurrentPerLvstore": Optional. The number of copies allowed to run simultaneously per source lvstore.
+//		DefaultMaxConcurrentPerLvstore is used if this is 0, and a negative value means no limit.
+func NewManager(spdkCli *client.Client, pollInterval time.Duration, maxConcurrentPerLvstore int) (*Manager, error) {
+	if spdkCli == nil {
+		return nil, fmt.Errorf("empty SPDK client for shallow copy manager creation")
+	}
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	if maxConcurrentPerLvstore == 0 {
+		maxConcurrentPerLvstore = DefaultMaxConcurrentPerLvstore
+	}
+
+	return &Manager{
+		spdkCli: spdkCli,
+
+		pollInterval:            pollInterval,
+		maxConcurrentPerLvstore: maxConcurrentPerLvstore,
+
+		jobs:     map[uint64]*Job{},
+		lvsSlots: map[string]*lvstoreSlots{},
+	}, nil
+}
+
+// Start queues a shallow copy of a snapshot lvol to a bdev and returns immediately.
+// The copy is started once a slot of the source lvstore is available.
+//
+//	"srcLvolName": Required. UUID or alias of the snapshot lvol to copy from.
+//
+//	"dstBdevName": Required. Name of the bdev that acts as destination for the copy.
+func (m *Manager) Start(ctx context.Context, srcLvolName, dstBdevName string) (*Job, error) {
+	if srcLvolName == "" || dstBdevName == "" {
+		return nil, fmt.Errorf("both source lvol and destination bdev are required for shallow copy")
+	}
+
+	srcLvol, err := m.spdkCli.BdevLvolGetByName(srcLvolName, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get source lvol %s for shallow copy", srcLvolName)
+	}
+	lvsUUID := srcLvol.DriverSpecific.Lvol.LvolStoreUUID
+	lvsList, err := m.spdkCli.BdevLvolGetLvstore("", lvsUUID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get lvstore %s of source lvol %s for shallow copy", lvsUUID, srcLvolName)
+	}
+	if len(lvsList) != 1 {
+		return nil, fmt.Errorf("zero or multiple lvstores with UUID %s found", lvsUUID)
+	}
+
+	m.Lock()
+	m.jobCounter++
+	job := newJob(ctx, m.jobCounter, srcLvolName, dstBdevName, lvsUUID, lvsList[0].ClusterSize)
+	m.jobs[job.ID()] = job
+	slots := m.acquireLvstoreSlotsWithoutLock(lvsUUID)
+	m.Unlock()
+
+	go m.run(job, slots)
+
+	return job, nil
+}
+
+// Get returns the job with the given ID.
+func (m *Manager) Get(jobID uint64) (*Job, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	job, exists := m.jobs[jobID]
+	if !exists {
+		return nil, fmt.Errorf("cannot find shallow copy job %d", jobID)
+	}
+	return job, nil
+}
+
+// List returns the latest progress of all jobs that have not been removed.
+func (m *Manager) List() []Progress {
+	m.RLock()
+	defer m.RUnlock()
+
+	progressList := make([]Progress, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		progressList = append(progressList, job.Status())
+	}
+	return progressList
+}
+
+// Remove forgets a finished job.
+func (m *Manager) Remove(jobID uint64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	job, exists := m.jobs[jobID]
+	if !exists {
+		return nil
+	}
+	select {
+	case <-job.Done():
+	default:
+		return fmt.Errorf("cannot remove unfinished shallow copy job %d", jobID)
+	}
+	delete(m.jobs, jobID)
+	return nil
+}
+
+func (m *Manager) acquireLvstoreSlotsWithoutLock(lvsUUID string) chan struct{} {
+	if m.maxConcurrentPerLvstore < 0 {
+		return nil
+	}
+	lvsSlots, exists := m.lvsSlots[lvsUUID]
+	if !exists {
+		lvsSlots = &lvstoreSlots{
+			slots: make(chan struct{}, m.maxConcurrentPerLvstore),
+		}
+		m.lvsSlots[lvsUUID] = lvsSlots
+	}
+	lvsSlots.refs++
+	return lvsSlots.slots
+}
+
+func (m *Manager) releaseLvstoreSlots(lvsUUID string) {
+	m.Lock()
+	defer m.Unlock()
+
+	lvsSlots, exists := m.lvsSlots[lvsUUID]
+	if !exists {
+		return
+	}
+	lvsSlots.refs--
+	if lvsSlots.refs <= 0 {
+		delete(m.lvsSlots, lvsUUID)
+	}
+}
+
+func (m *Manager) run(job *Job, slots chan struct{}) {
+	status := job.Status()
+	if slots != nil {
+		defer m.releaseLvstoreSlots(status.LvsUUID)
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"jobID":       status.JobID,
+		"srcLvolName": status.SrcLvolName,
+		"dstBdevName": status.DstBdevName,
+	})
+
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		case <-job.ctx.Done():
+			job.finish(JobStateCanceled, ErrJobCanceled)
+			return
+		}
+	}
+
+	operationID, err := m.spdkCli.BdevLvolStartShallowCopy(status.SrcLvolName, status.DstBdevName)
+	if err != nil {
+		job.finish(JobStateError, errors.Wrap(err, "failed to start shallow copy"))
+		return
+	}
+	job.setOperationID(operationID)
+	log = log.WithField("operationID", operationID)
+	log.Info("Started shallow copy")
+
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-job.ctx.Done():
+			log.Warn("Stopped tracking shallow copy since the job is canceled")
+			job.finish(JobStateCanceled, ErrJobCanceled)
+			return
+		case <-ticker.C:
+		}
+
+		copyStatus, err := m.spdkCli.BdevLvolCheckShallowCopy(operationID)
+		if err != nil {
+			job.finish(JobStateError, errors.Wrapf(err, "failed to check shallow copy operation %d", operationID))
+			return
+		}
+
+		switch copyStatus.State {
+		case types.ShallowCopyStateInProgress:
+			job.update(JobStateInProgress, copyStatus.CopiedClusters, copyStatus.TotalClusters, time.Now())
+		case types.ShallowCopyStateComplete:
+			job.update(JobStateComplete, copyStatus.CopiedClusters, copyStatus.TotalClusters, time.Now())
+			job.finish(JobStateComplete, nil)
+			log.Info("Completed shallow copy")
+			return
+		case types.ShallowCopyStateError:
+			job.update(JobStateError, copyStatus.CopiedClusters, copyStatus.TotalClusters, time.Now())
+			job.finish(JobStateError, &CopyError{
+				OperationID: operationID,
+				SrcLvolName: status.SrcLvolName,
+				DstBdevName: status.DstBdevName,
+				Message:     copyStatus.Error,
+			})
+			log.Errorf("Shallow copy failed: %v", copyStatus.Error)
+			return
+		default:
+			job.finish(JobStateError, fmt.Errorf("unknown state %q of shallow copy operation %d", copyStatus.State, operationID))
+			return
+		}
+	}
+}
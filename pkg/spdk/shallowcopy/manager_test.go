@@ -0,0 +1,251 @@
+package shallowcopy
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc"
+	"github.com/longhorn/go-spdk-helper/pkg/jsonrpc/jsonrpctest"
+	"github.com/longhorn/go-spdk-helper/pkg/spdk/client"
+	"github.com/longhorn/go-spdk-helper/pkg/types"
+
+	spdktypes "github.com/longhorn/go-spdk-helper/pkg/spdk/types"
+)
+
+// fakeCopier serves the shallow copies of the snapshots in memory.
+// The operation ID of a copy is the index of its source snapshot plus 1,
+// and a copy stays in progress until its state is set.
+type fakeCopier struct {
+	sync.Mutex
+
+	// lvsUUIDs are the lvstore UUIDs keyed by the snapshot names
+	lvsUUIDs  map[string]string
+	snapshots []string
+	started   []string
+	states    map[uint32]string
+
+	server *jsonrpctest.Server
+}
+
+func newFakeCopier(lvsUUIDs map[string]string, snapshots ...string) *fakeCopier {
+	f := &fakeCopier{
+		lvsUUIDs:  lvsUUIDs,
+		snapshots: snapshots,
+		states:    map[uint32]string{},
+	}
+	f.server = jsonrpctest.NewServer(map[string]jsonrpctest.Handler{
+		"bdev_get_bdevs": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevGetBdevsRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			return []spdktypes.BdevInfo{{
+				BdevInfoBasic: spdktypes.BdevInfoBasic{
+					Name:        req.Name,
+					ProductName: spdktypes.BdevProductNameLvol,
+				},
+				DriverSpecific: &spdktypes.BdevDriverSpecific{
+					Lvol: &spdktypes.BdevDriverSpecificLvol{
+						LvolStoreUUID: f.lvsUUIDs[req.Name],
+						Snapshot:      true,
+					},
+				},
+			}}, nil
+		},
+		"bdev_lvol_get_lvstores": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolGetLvstoreRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			return []spdktypes.LvstoreInfo{{UUID: req.UUID, ClusterSize: types.MiB}}, nil
+		},
+		"bdev_lvol_start_shallow_copy": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.BdevLvolShallowCopyRequest{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			f.Lock()
+			defer f.Unlock()
+			f.started = append(f.started, req.SrcLvolName)
+			for idx, snapshot := range f.snapshots {
+				if snapshot == req.SrcLvolName {
+					return spdktypes.ShallowCopy{OperationId: uint32(idx + 1)}, nil
+				}
+			}
+			return nil, &jsonrpc.ResponseError{Code: jsonrpc.RespErrorCodeNoSuchDevice, Message: "No such device"}
+		},
+		"bdev_lvol_check_shallow_copy": func(params json.RawMessage) (interface{}, *jsonrpc.ResponseError) {
+			req := spdktypes.ShallowCopy{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, &jsonrpc.ResponseError{Code: -22, Message: jsonrpc.RespErrorMsg(err.Error())}
+			}
+			f.Lock()
+			defer f.Unlock()
+			switch state := f.states[req.OperationId]; state {
+			case types.ShallowCopyStateComplete:
+				return spdktypes.ShallowCopyStatus{State: state, CopiedClusters: 10, TotalClusters: 10}, nil
+			case types.ShallowCopyStateError:
+				return spdktypes.ShallowCopyStatus{State: state, CopiedClusters: 5, TotalClusters: 10, Error: "Input/output error"}, nil
+			default:
+				return spdktypes.ShallowCopyStatus{State: types.ShallowCopyStateInProgress, CopiedClusters: 5, TotalClusters: 10}, nil
+			}
+		},
+	})
+	return f
+}
+
+func (f *fakeCopier) getStarted() []string {
+	f.Lock()
+	defer f.Unlock()
+	return append([]string{}, f.started...)
+}
+
+func (f *fakeCopier) setState(operationID uint32, state string) {
+	f.Lock()
+	defer f.Unlock()
+	f.states[operationID] = state
+}
+
+func newTestManager(c *C, server *jsonrpctest.Server, maxConcurrentPerLvstore int) (*Manager, func()) {
+	conn, closeConn := server.Dial()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m, err := NewManager(client.NewClientWithConn(ctx, conn), time.Millisecond, maxConcurrentPerLvstore)
+	c.Assert(err, IsNil)
+	return m, func() {
+		cancel()
+		closeConn()
+	}
+}
+
+func waitForStarted(c *C, copier *fakeCopier, count int) {
+	for i := 0; i < 500; i++ {
+		if len(copier.getStarted()) >= count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("timeout waiting for %d started shallow copies", count)
+}
+
+func waitForJob(c *C, job *Job) {
+	select {
+	case <-job.Done():
+	case <-time.After(5 * time.Second):
+		c.Fatalf("timeout waiting for shallow copy job %d", job.ID())
+	}
+}
+
+func (s *TestSuite) TestManagerConcurrencyLimit(c *C) {
+	copier := newFakeCopier(map[string]string{"snap1": "lvs1", "snap2": "lvs1", "snap3": "lvs2"}, "snap1", "snap2", "snap3")
+	m, closeFn := newTestManager(c, copier.server, 0)
+	defer closeFn()
+
+	jobs := []*Job{}
+	for _, snapshot := range []string{"snap1", "snap2", "snap3"} {
+		job, err := m.Start(context.Background(), snapshot, snapshot+"-dst")
+		c.Assert(err, IsNil)
+		jobs = append(jobs, job)
+		if snapshot == "snap1" {
+			waitForStarted(c, copier, 1)
+		}
+	}
+
+	// Only one copy runs per lvstore, and the copy of the other lvstore is not held back
+	time.Sleep(50 * time.Millisecond)
+	started := copier.getStarted()
+	sort.Strings(started)
+	c.Assert(started, DeepEquals, []string{"snap1", "snap3"})
+	c.Assert(jobs[0].Status().State, Equals, JobStateInProgress)
+	c.Assert(jobs[1].Status().State, Equals, JobStateQueued)
+	c.Assert(jobs[2].Status().State, Equals, JobStateInProgress)
+
+	copier.setState(1, types.ShallowCopyStateComplete)
+	waitForJob(c, jobs[0])
+	c.Assert(jobs[0].Err(), IsNil)
+	copier.setState(2, types.ShallowCopyStateComplete)
+	copier.setState(3, types.ShallowCopyStateComplete)
+	waitForJob(c, jobs[1])
+	waitForJob(c, jobs[2])
+	c.Assert(copier.getStarted(), HasLen, 3)
+
+	for _, job := range jobs {
+		c.Assert(job.Err(), IsNil)
+		status := job.Status()
+		c.Assert(status.State, Equals, JobStateComplete)
+		c.Assert(status.Percentage(), Equals, float64(100))
+		c.Assert(status.ETA, Equals, time.Duration(0))
+	}
+
+	// The slots of the lvstores without any job left are pruned
+	m.RLock()
+	c.Assert(m.lvsSlots, HasLen, 0)
+	m.RUnlock()
+
+	c.Assert(m.List(), HasLen, 3)
+	c.Assert(m.Remove(jobs[0].ID()), IsNil)
+	c.Assert(m.List(), HasLen, 2)
+	_, err := m.Get(jobs[0].ID())
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite) TestManagerTerminalStates(c *C) {
+	copier := newFakeCopier(map[string]string{"snap1": "lvs1", "snap2": "lvs1", "snap3": "lvs2"}, "snap1", "snap2")
+	m, closeFn := newTestManager(c, copier.server, 1)
+	defer closeFn()
+
+	failedJob, err := m.Start(context.Background(), "snap1", "snap1-dst")
+	c.Assert(err, IsNil)
+	waitForStarted(c, copier, 1)
+	queuedJob, err := m.Start(context.Background(), "snap2", "snap2-dst")
+	c.Assert(err, IsNil)
+
+	// A running job cannot be removed, and a queued one is never started once canceled
+	c.Assert(m.Remove(failedJob.ID()), ErrorMatches, "cannot remove unfinished shallow copy job .*")
+	queuedJob.Cancel()
+	waitForJob(c, queuedJob)
+	c.Assert(queuedJob.Err(), Equals, ErrJobCanceled)
+	c.Assert(queuedJob.Status().State, Equals, JobStateCanceled)
+
+	copier.setState(1, types.ShallowCopyStateError)
+	waitForJob(c, failedJob)
+	c.Assert(IsCopyError(failedJob.Err()), Equals, true)
+	c.Assert(failedJob.Err(), ErrorMatches, "shallow copy operation 1 from snap1 to snap1-dst failed: Input/output error")
+	c.Assert(failedJob.Status().State, Equals, JobStateError)
+	c.Assert(copier.getStarted(), DeepEquals, []string{"snap1"})
+
+	// A copy that SPDK refuses to start fails the job
+	startFailedJob, err := m.Start(context.Background(), "snap3", "snap3-dst")
+	c.Assert(err, IsNil)
+	waitForJob(c, startFailedJob)
+	c.Assert(startFailedJob.Err(), ErrorMatches, "failed to start shallow copy: .*No such device.*")
+	c.Assert(startFailedJob.Status().State, Equals, JobStateError)
+
+	m.RLock()
+	c.Assert(m.lvsSlots, HasLen, 0)
+	m.RUnlock()
+}
+
+func (s *TestSuite) TestJobFinalEvent(c *C) {
+	job := newJob(context.Background(), 1, "snap1", "snap1-dst", "lvs1", types.MiB)
+	job.setOperationID(1)
+	// Nobody receives the events, so the channel is full
+	for copied := uint64(0); copied < 2*progressChanSize; copied++ {
+		job.update(JobStateInProgress, copied, 100, time.Now())
+	}
+	job.update(JobStateComplete, 100, 100, time.Now())
+	job.finish(JobStateComplete, nil)
+
+	events := []Progress{}
+	for event := range job.Progress() {
+		events = append(events, event)
+	}
+	c.Assert(events, HasLen, progressChanSize)
+	c.Assert(events[len(events)-1].State, Equals, JobStateComplete)
+	c.Assert(events[len(events)-1].CopiedClusters, Equals, uint64(100))
+}
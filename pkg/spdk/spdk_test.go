@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -602,3 +603,290 @@ func (s *TestSuite) TestSPDKEngineSuspend(c *C) {
 	err = i.Resume()
 	c.Assert(err, IsNil)
 }
+
+func (s *TestSuite) TestSPDKDeviceGrow(c *C) {
+	fmt.Println("Testing SPDK Device Grow")
+
+	ne, err := util.NewExecutor(commontypes.ProcDirectory)
+	c.Assert(err, IsNil)
+
+	LaunchTestSPDKTarget(c, ne.Execute)
+	PrepareDeviceFile(c)
+	defer func() {
+		os.RemoveAll(defaultDevicePath)
+	}()
+
+	spdkCli, err := client.NewClient(context.Background())
+	c.Assert(err, IsNil)
+
+	// Do blindly cleanup
+	err = spdkCli.DeleteDevice(defaultDeviceName, defaultDeviceName)
+	if err != nil {
+		c.Assert(jsonrpc.IsJSONRPCRespErrorNoSuchDevice(err), Equals, true)
+	}
+
+	bdevAioName, lvsName, lvsUUID, err := spdkCli.AddDevice(defaultDevicePath, defaultDeviceName, types.MiB)
+	c.Assert(err, IsNil)
+	defer func() {
+		err := spdkCli.DeleteDevice(bdevAioName, lvsName)
+		c.Assert(err, IsNil)
+	}()
+	c.Assert(lvsUUID, Not(Equals), "")
+
+	// Nothing changes if the device is not enlarged
+	info, err := spdkCli.GrowDevice(bdevAioName, lvsName, time.Second)
+	c.Assert(err, IsNil)
+	c.Assert(info.NumBlocksAfter, Equals, info.NumBlocksBefore)
+	c.Assert(info.TotalDataClustersAfter, Equals, info.TotalDataClustersBefore)
+	c.Assert(info.FreeClustersAfter, Equals, info.FreeClustersBefore)
+
+	// A retry after a call failing between the rescan and the lvstore growing still grows the lvstore,
+	// even though the aio bdev size does not change anymore
+	err = os.Truncate(defaultDevicePath, int64(2*defaultDeviceSize))
+	c.Assert(err, IsNil)
+	_, err = spdkCli.BdevAioRescan(bdevAioName)
+	c.Assert(err, IsNil)
+
+	start := time.Now()
+	info, err = spdkCli.GrowDevice(bdevAioName, lvsName, time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(start) < time.Minute, Equals, true)
+	c.Assert(info.NumBlocksAfter, Equals, info.NumBlocksBefore)
+	c.Assert(info.TotalDataClustersAfter > info.TotalDataClustersBefore, Equals, true)
+	c.Assert(info.FreeClustersAfter-info.FreeClustersBefore, Equals, info.TotalDataClustersAfter-info.TotalDataClustersBefore)
+
+	grownDeviceSize := 3 * defaultDeviceSize
+	err = os.Truncate(defaultDevicePath, int64(grownDeviceSize))
+	c.Assert(err, IsNil)
+
+	info, err = spdkCli.GrowDevice(bdevAioName, lvsName, 0)
+	c.Assert(err, IsNil)
+	c.Assert(2*info.NumBlocksAfter, Equals, 3*info.NumBlocksBefore)
+	c.Assert(info.TotalDataClustersAfter > info.TotalDataClustersBefore, Equals, true)
+	c.Assert(info.FreeClustersAfter-info.FreeClustersBefore, Equals, info.TotalDataClustersAfter-info.TotalDataClustersBefore)
+
+	bdevAioInfoList, err := spdkCli.BdevAioGet(bdevAioName, 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(bdevAioInfoList), Equals, 1)
+	c.Assert(uint64(bdevAioInfoList[0].BlockSize)*bdevAioInfoList[0].NumBlocks, Equals, grownDeviceSize)
+}
+
+func (s *TestSuite) TestSPDKNvmfTLS(c *C) {
+	fmt.Println("Testing SPDK NVMe-oF TLS")
+
+	ne, err := util.NewExecutor(commontypes.ProcDirectory)
+	c.Assert(err, IsNil)
+
+	LaunchTestSPDKTarget(c, ne.Execute)
+	PrepareDeviceFile(c)
+	defer func() {
+		os.RemoveAll(defaultDevicePath)
+	}()
+
+	spdkCli, err := client.NewClient(context.Background())
+	c.Assert(err, IsNil)
+
+	// Do blindly cleanup
+	err = spdkCli.DeleteDevice(defaultDeviceName, defaultDeviceName)
+	if err != nil {
+		c.Assert(jsonrpc.IsJSONRPCRespErrorNoSuchDevice(err), Equals, true)
+	}
+
+	bdevAioName, lvsName, lvsUUID, err := spdkCli.AddDevice(defaultDevicePath, defaultDeviceName, types.MiB)
+	c.Assert(err, IsNil)
+	defer func() {
+		err := spdkCli.DeleteDevice(bdevAioName, lvsName)
+		c.Assert(err, IsNil)
+	}()
+
+	lvolName := "test-tls-lvol"
+	lvolUUID, err := spdkCli.BdevLvolCreate("", lvsUUID, lvolName, defaultLvolSizeInMiB, "", true)
+	c.Assert(err, IsNil)
+	defer func() {
+		deleted, err := spdkCli.BdevLvolDelete(lvolUUID)
+		c.Assert(err, IsNil)
+		c.Assert(deleted, Equals, true)
+	}()
+
+	// The PSK in the interchange format, which is the one used by the SPDK TLS tests
+	pskName := "test-tls-psk"
+	pskPath := filepath.Join("/tmp", pskName)
+	psk := "NVMeTLSkey-1:01:MDAxMTIyMzM0NDU1NjY3Nzg4OTlhYWJiY2NkZGVlZmZwJEiQ:"
+	err = os.WriteFile(pskPath, []byte(psk), 0600)
+	c.Assert(err, IsNil)
+	defer func() {
+		os.RemoveAll(pskPath)
+	}()
+	added, err := spdkCli.KeyringFileAddKey(pskName, pskPath)
+	c.Assert(err, IsNil)
+	c.Assert(added, Equals, true)
+	defer func() {
+		removed, err := spdkCli.KeyringFileRemoveKey(pskName)
+		c.Assert(err, IsNil)
+		c.Assert(removed, Equals, true)
+	}()
+
+	tlsPort := "4430"
+	nqn := types.GetNQN(lvolName)
+	hostNQN := types.GetNQN("test-tls-host")
+
+	if _, err := spdkCli.NvmfCreateTransport(spdktypes.NvmeTransportTypeTCP); err != nil {
+		c.Assert(jsonrpc.IsJSONRPCRespErrorTransportTypeAlreadyExists(err), Equals, true)
+	}
+	_, err = spdkCli.NvmfCreateSubsystem(nqn)
+	c.Assert(err, IsNil)
+	defer func() {
+		err = spdkCli.StopExposeBdev(nqn)
+		c.Assert(err, IsNil)
+	}()
+	_, err = spdkCli.NvmfSubsystemAddNs(nqn, lvolUUID, "")
+	c.Assert(err, IsNil)
+	_, err = spdkCli.NvmfSubsystemAddListenerWithOptions(client.NvmfSubsystemAddListenerOptions{
+		Nqn:           nqn,
+		Traddr:        types.LocalIP,
+		Trsvcid:       tlsPort,
+		Trtype:        spdktypes.NvmeTransportTypeTCP,
+		Adrfam:        spdktypes.NvmeAddressFamilyIPv4,
+		SecureChannel: true,
+	})
+	c.Assert(err, IsNil)
+	err = spdkCli.SetSubsystemHosts(nqn, []spdktypes.NvmfSubsystemHost{{Nqn: hostNQN, Psk: pskName}})
+	c.Assert(err, IsNil)
+
+	subsystemList, err := spdkCli.NvmfGetSubsystems(nqn, "")
+	c.Assert(err, IsNil)
+	c.Assert(len(subsystemList), Equals, 1)
+	c.Assert(subsystemList[0].AllowAnyHost, Equals, false)
+	c.Assert(len(subsystemList[0].Hosts), Equals, 1)
+	c.Assert(subsystemList[0].Hosts[0].Nqn, Equals, hostNQN)
+
+	attachOpts := client.BdevNvmeAttachControllerOptions{
+		Name:                 "tlsnvme",
+		Subnqn:               nqn,
+		Traddr:               types.LocalIP,
+		Trsvcid:              tlsPort,
+		Trtype:               spdktypes.NvmeTransportTypeTCP,
+		Adrfam:               spdktypes.NvmeAddressFamilyIPv4,
+		CtrlrLossTimeoutSec:  types.DefaultCtrlrLossTimeoutSec,
+		ReconnectDelaySec:    types.DefaultReconnectDelaySec,
+		FastIOFailTimeoutSec: types.DefaultFastIOFailTimeoutSec,
+		Hostnqn:              hostNQN,
+	}
+
+	// The secure channel listener rejects the host without the PSK
+	_, err = spdkCli.BdevNvmeAttachControllerWithOptions(attachOpts)
+	c.Assert(err, NotNil)
+
+	attachOpts.Psk = pskName
+	bdevNameList, err := spdkCli.BdevNvmeAttachControllerWithOptions(attachOpts)
+	c.Assert(err, IsNil)
+	c.Assert(len(bdevNameList), Equals, 1)
+	defer func() {
+		detached, err := spdkCli.BdevNvmeDetachController(attachOpts.Name)
+		c.Assert(err, IsNil)
+		c.Assert(detached, Equals, true)
+	}()
+
+	bdevNvmeList, err := spdkCli.BdevNvmeGet(bdevNameList[0], 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(bdevNvmeList), Equals, 1)
+	c.Assert(bdevNvmeList[0].NumBlocks*uint64(bdevNvmeList[0].BlockSize), Equals, defaultLvolSizeInMiB*types.MiB)
+
+	// Setting the same hosts again keeps the connected controller rather than re-adding the host
+	controllerList, err := spdkCli.NvmfSubsystemGetControllers(nqn, "")
+	c.Assert(err, IsNil)
+	c.Assert(len(controllerList), Equals, 1)
+	err = spdkCli.SetSubsystemHosts(nqn, []spdktypes.NvmfSubsystemHost{{Nqn: hostNQN, Psk: pskName}})
+	c.Assert(err, IsNil)
+	newControllerList, err := spdkCli.NvmfSubsystemGetControllers(nqn, "")
+	c.Assert(err, IsNil)
+	c.Assert(newControllerList, DeepEquals, controllerList)
+
+	// The Linux nvme driver connects via nvme-cli as well, and is rejected without TLS
+	connectOpts := initiator.ConnectOptions{HostNQN: hostNQN}
+	_, err = initiator.ConnectTargetWithOptions(types.LocalIP, tlsPort, nqn, connectOpts, ne)
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.Error(), psk), Equals, false)
+
+	connectOpts.TLSKey = psk
+	controllerName, err := initiator.ConnectTargetWithOptions(types.LocalIP, tlsPort, nqn, connectOpts, ne)
+	c.Assert(err, IsNil)
+	c.Assert(controllerName, Not(Equals), "")
+	defer func() {
+		err := initiator.DisconnectTarget(nqn, ne)
+		c.Assert(err, IsNil)
+	}()
+
+	devices, err := initiator.GetDevices(types.LocalIP, tlsPort, nqn, ne)
+	c.Assert(err, IsNil)
+	c.Assert(len(devices), Equals, 1)
+}
+
+func (s *TestSuite) TestSPDKNvmfIPv6(c *C) {
+	fmt.Println("Testing SPDK NVMe-oF over IPv6")
+
+	ne, err := util.NewExecutor(commontypes.ProcDirectory)
+	c.Assert(err, IsNil)
+
+	LaunchTestSPDKTarget(c, ne.Execute)
+	PrepareDeviceFile(c)
+	defer func() {
+		os.RemoveAll(defaultDevicePath)
+	}()
+
+	spdkCli, err := client.NewClient(context.Background())
+	c.Assert(err, IsNil)
+
+	// Do blindly cleanup
+	err = spdkCli.DeleteDevice(defaultDeviceName, defaultDeviceName)
+	if err != nil {
+		c.Assert(jsonrpc.IsJSONRPCRespErrorNoSuchDevice(err), Equals, true)
+	}
+
+	bdevAioName, lvsName, lvsUUID, err := spdkCli.AddDevice(defaultDevicePath, defaultDeviceName, types.MiB)
+	c.Assert(err, IsNil)
+	defer func() {
+		err := spdkCli.DeleteDevice(bdevAioName, lvsName)
+		c.Assert(err, IsNil)
+	}()
+
+	lvolName := "test-ipv6-lvol"
+	lvolUUID, err := spdkCli.BdevLvolCreate("", lvsUUID, lvolName, defaultLvolSizeInMiB, "", true)
+	c.Assert(err, IsNil)
+	defer func() {
+		deleted, err := spdkCli.BdevLvolDelete(lvolUUID)
+		c.Assert(err, IsNil)
+		c.Assert(deleted, Equals, true)
+	}()
+
+	// The bracketed literal is accepted as well, and the address family is inferred
+	nqn := types.GetNQN(lvolName)
+	err = spdkCli.StartExposeBdev(nqn, lvolUUID, "", "["+types.LocalIPv6+"]", defaultPort1)
+	c.Assert(err, IsNil)
+	defer func() {
+		err = spdkCli.StopExposeBdev(nqn)
+		c.Assert(err, IsNil)
+	}()
+
+	listenerList, err := spdkCli.NvmfSubsystemGetListeners(nqn, "")
+	c.Assert(err, IsNil)
+	c.Assert(len(listenerList), Equals, 1)
+	c.Assert(listenerList[0].Address.Traddr, Equals, types.LocalIPv6)
+	c.Assert(listenerList[0].Address.Adrfam, Equals, spdktypes.NvmeAddressFamilyIPv6)
+
+	controllerName := "ipv6nvme"
+	bdevNameList, err := spdkCli.BdevNvmeAttachController(controllerName, nqn, types.LocalIPv6, defaultPort1, spdktypes.NvmeTransportTypeTCP, "",
+		types.DefaultCtrlrLossTimeoutSec, types.DefaultReconnectDelaySec, types.DefaultFastIOFailTimeoutSec, types.DefaultMultipath)
+	c.Assert(err, IsNil)
+	c.Assert(len(bdevNameList), Equals, 1)
+	defer func() {
+		detached, err := spdkCli.BdevNvmeDetachController(controllerName)
+		c.Assert(err, IsNil)
+		c.Assert(detached, Equals, true)
+	}()
+
+	bdevNvmeList, err := spdkCli.BdevNvmeGet(bdevNameList[0], 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(bdevNvmeList), Equals, 1)
+	c.Assert(bdevNvmeList[0].NumBlocks*uint64(bdevNvmeList[0].BlockSize), Equals, defaultLvolSizeInMiB*types.MiB)
+}
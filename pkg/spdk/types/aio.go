@@ -21,3 +21,7 @@ type BdevAioCreateRequest struct {
 type BdevAioDeleteRequest struct {
 	Name string `json:"name"`
 }
+
+type BdevAioRescanRequest struct {
+	Name string `json:"name"`
+}
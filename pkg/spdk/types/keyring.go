@@ -0,0 +1,21 @@
+package types
+
+type KeyringFileAddKeyRequest struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type KeyringFileRemoveKeyRequest struct {
+	Name string `json:"name"`
+}
+
+type KeyringGetKeysRequest struct {
+}
+
+type KeyringKey struct {
+	Name    string `json:"name"`
+	Removed bool   `json:"removed"`
+	Probed  bool   `json:"probed"`
+	Refcnt  int32  `json:"refcnt"`
+	Path    string `json:"path,omitempty"`
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -57,9 +58,9 @@ type BdevLvolCreateLvstoreRequest struct {
 	BdevName string `json:"bdev_name"`
 	LvsName  string `json:"lvs_name"`
 
-	ClusterSz uint32 `json:"cluster_sz,omitempty"`
-	// ClearMethod               string `json:"clear_method,omitempty"`
-	// NumMdPagesPerClusterRatio uint32 `json:"num_md_pages_per_cluster_ratio,omitempty"`
+	ClusterSz                 uint32              `json:"cluster_sz,omitempty"`
+	ClearMethod               BdevLvolClearMethod `json:"clear_method,omitempty"`
+	NumMdPagesPerClusterRatio uint32              `json:"num_md_pages_per_cluster_ratio,omitempty"`
 }
 
 type BdevLvolDeleteLvstoreRequest struct {
@@ -100,9 +101,11 @@ type BdevLvolCreateRequest struct {
 	LvsName       string              `json:"lvs_name,omitempty"`
 	UUID          string              `json:"uuid,omitempty"`
 	LvolName      string              `json:"lvol_name"`
+	LvolUUID      string              `json:"lvol_uuid,omitempty"`
 	SizeInMib     uint64              `json:"size_in_mib"`
 	ClearMethod   BdevLvolClearMethod `json:"clear_method,omitempty"`
 	ThinProvision bool                `json:"thin_provision,omitempty"`
+	Xattrs        map[string]string   `json:"xattrs,omitempty"`
 }
 
 type BdevLvolDeleteRequest struct {
@@ -176,6 +179,116 @@ type BdevLvolStopSnapshotChecksumRequest struct {
 	Name string `json:"name"`
 }
 
+type BdevLvolSetReadOnlyRequest struct {
+	Name string `json:"name"`
+}
+
+type BdevLvolInflateRequest struct {
+	Name string `json:"name"`
+}
+
+type BdevLvolGrowLvstoreRequest struct {
+	UUID    string `json:"uuid,omitempty"`
+	LvsName string `json:"lvs_name,omitempty"`
+}
+
+type BdevLvolGetLvolsRequest struct {
+	LvsUUID string `json:"lvs_uuid,omitempty"`
+	LvsName string `json:"lvs_name,omitempty"`
+}
+
+type BdevLvolCreateEsnapCloneRequest struct {
+	EsnapUUID string `json:"esnap_uuid"`
+	LvsName   string `json:"lvs_name,omitempty"`
+	LvsUUID   string `json:"lvs_uuid,omitempty"`
+	CloneName string `json:"clone_name"`
+}
+
+// LvstoreUsage is the capacity report of a logical volume store.
+type LvstoreUsage struct {
+	Name string `json:"name"`
+	UUID string `json:"uuid"`
+
+	ClusterSize uint64 `json:"cluster_size"`
+	TotalBytes  uint64 `json:"total_bytes"`
+	FreeBytes   uint64 `json:"free_bytes"`
+
+	// ProvisionedBytes is the sum of the sizes of all lvols except for snapshots.
+	ProvisionedBytes uint64 `json:"provisioned_bytes"`
+	// AllocatedBytes is the sum of the clusters allocated by all lvols including snapshots.
+	AllocatedBytes uint64 `json:"allocated_bytes"`
+	// SnapshotBytes is the part of AllocatedBytes held by snapshots only.
+	SnapshotBytes uint64 `json:"snapshot_bytes"`
+	// OvercommitRatio is ProvisionedBytes divided by TotalBytes. A value larger than 1 means the lvstore is overcommitted.
+	OvercommitRatio float64 `json:"overcommit_ratio"`
+
+	NumLvols     int `json:"num_lvols"`
+	NumSnapshots int `json:"num_snapshots"`
+
+	// TopConsumers are the lvols allocating the most clusters, in descending order.
+	TopConsumers []LvolUsage `json:"top_consumers"`
+}
+
+type LvolUsage struct {
+	Alias            string `json:"alias"`
+	UUID             string `json:"uuid"`
+	Snapshot         bool   `json:"snapshot"`
+	ProvisionedBytes uint64 `json:"provisioned_bytes"`
+	AllocatedBytes   uint64 `json:"allocated_bytes"`
+}
+
+// GetLvstoreUsage computes the capacity report of the lvstore from the lvol bdevs on it.
+// Lvols of other lvstores are ignored. topN limits the length of TopConsumers, and 0 means no limit.
+func GetLvstoreUsage(lvs *LvstoreInfo, bdevLvolList []BdevInfo, topN int) *LvstoreUsage {
+	usage := &LvstoreUsage{
+		Name:         lvs.Name,
+		UUID:         lvs.UUID,
+		ClusterSize:  lvs.ClusterSize,
+		TotalBytes:   lvs.TotalDataClusters * lvs.ClusterSize,
+		FreeBytes:    lvs.FreeClusters * lvs.ClusterSize,
+		TopConsumers: []LvolUsage{},
+	}
+
+	for _, b := range bdevLvolList {
+		if GetBdevType(&b) != BdevTypeLvol || b.DriverSpecific.Lvol.LvolStoreUUID != lvs.UUID {
+			continue
+		}
+
+		lvolUsage := LvolUsage{
+			UUID:             b.UUID,
+			Snapshot:         b.DriverSpecific.Lvol.Snapshot,
+			ProvisionedBytes: uint64(b.BlockSize) * b.NumBlocks,
+			AllocatedBytes:   b.DriverSpecific.Lvol.NumAllocatedClusters * lvs.ClusterSize,
+		}
+		if len(b.Aliases) > 0 {
+			lvolUsage.Alias = b.Aliases[0]
+		}
+
+		usage.AllocatedBytes += lvolUsage.AllocatedBytes
+		if lvolUsage.Snapshot {
+			usage.NumSnapshots++
+			usage.SnapshotBytes += lvolUsage.AllocatedBytes
+		} else {
+			usage.NumLvols++
+			usage.ProvisionedBytes += lvolUsage.ProvisionedBytes
+		}
+		usage.TopConsumers = append(usage.TopConsumers, lvolUsage)
+	}
+
+	if usage.TotalBytes > 0 {
+		usage.OvercommitRatio = float64(usage.ProvisionedBytes) / float64(usage.TotalBytes)
+	}
+
+	sort.SliceStable(usage.TopConsumers, func(i, j int) bool {
+		return usage.TopConsumers[i].AllocatedBytes > usage.TopConsumers[j].AllocatedBytes
+	})
+	if topN > 0 && len(usage.TopConsumers) > topN {
+		usage.TopConsumers = usage.TopConsumers[:topN]
+	}
+
+	return usage
+}
+
 func GetLvolAlias(lvsName, lvolName string) string {
 	return fmt.Sprintf("%s/%s", lvsName, lvolName)
 }
@@ -0,0 +1,98 @@
+package types
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+const testClusterSize = 4 * 1024 * 1024
+
+func newTestLvol(lvsUUID, name string, numClusters, allocatedClusters uint64, thin, snapshot bool) BdevInfo {
+	return BdevInfo{
+		BdevInfoBasic: BdevInfoBasic{
+			Name:        name + "-uuid",
+			Aliases:     []string{"lvs/" + name},
+			ProductName: BdevProductNameLvol,
+			BlockSize:   4096,
+			NumBlocks:   numClusters * testClusterSize / 4096,
+			UUID:        name + "-uuid",
+		},
+		DriverSpecific: &BdevDriverSpecific{
+			Lvol: &BdevDriverSpecificLvol{
+				LvolStoreUUID:        lvsUUID,
+				ThinProvision:        thin,
+				NumAllocatedClusters: allocatedClusters,
+				Snapshot:             snapshot,
+			},
+		},
+	}
+}
+
+func (s *TestSuite) TestGetLvstoreUsage(c *C) {
+	lvs := &LvstoreInfo{
+		UUID:              "lvs-uuid",
+		Name:              "lvs",
+		TotalDataClusters: 100,
+		FreeClusters:      40,
+		ClusterSize:       testClusterSize,
+	}
+	bdevLvolList := []BdevInfo{
+		// A thick lvol allocates all its clusters on creation
+		newTestLvol("lvs-uuid", "thick", 30, 30, false, false),
+		// A thin lvol allocates the clusters written only, and its snapshot holds the rest
+		newTestLvol("lvs-uuid", "thin", 100, 10, true, false),
+		newTestLvol("lvs-uuid", "snap", 100, 20, true, true),
+		// The lvols of other lvstores and the other bdevs are ignored
+		newTestLvol("other-lvs-uuid", "other", 50, 50, false, false),
+		{
+			BdevInfoBasic: BdevInfoBasic{Name: "aio", ProductName: BdevProductNameAio, BlockSize: 4096, NumBlocks: 1024},
+			DriverSpecific: &BdevDriverSpecific{
+				Aio: &BdevDriverSpecificAio{FileName: "/dev/sdb"},
+			},
+		},
+	}
+
+	thick := LvolUsage{Alias: "lvs/thick", UUID: "thick-uuid", ProvisionedBytes: 30 * testClusterSize, AllocatedBytes: 30 * testClusterSize}
+	thin := LvolUsage{Alias: "lvs/thin", UUID: "thin-uuid", ProvisionedBytes: 100 * testClusterSize, AllocatedBytes: 10 * testClusterSize}
+	snap := LvolUsage{Alias: "lvs/snap", UUID: "snap-uuid", Snapshot: true, ProvisionedBytes: 100 * testClusterSize, AllocatedBytes: 20 * testClusterSize}
+
+	testCases := []struct {
+		name     string
+		topN     int
+		expected []LvolUsage
+	}{
+		{"no limit", 0, []LvolUsage{thick, snap, thin}},
+		{"limited", 2, []LvolUsage{thick, snap}},
+		{"limit larger than the lvol count", 10, []LvolUsage{thick, snap, thin}},
+	}
+
+	for _, testCase := range testCases {
+		comment := Commentf("test case %s", testCase.name)
+
+		usage := GetLvstoreUsage(lvs, bdevLvolList, testCase.topN)
+		c.Assert(usage.TopConsumers, DeepEquals, testCase.expected, comment)
+
+		// The totals do not depend on the limit
+		c.Assert(usage.Name, Equals, "lvs", comment)
+		c.Assert(usage.TotalBytes, Equals, uint64(100*testClusterSize), comment)
+		c.Assert(usage.FreeBytes, Equals, uint64(40*testClusterSize), comment)
+		// Snapshots are not counted as provisioned since their size is provisioned by the lvols on them
+		c.Assert(usage.ProvisionedBytes, Equals, uint64(130*testClusterSize), comment)
+		c.Assert(usage.AllocatedBytes, Equals, uint64(60*testClusterSize), comment)
+		c.Assert(usage.SnapshotBytes, Equals, uint64(20*testClusterSize), comment)
+		c.Assert(usage.OvercommitRatio, Equals, 1.3, comment)
+		c.Assert(usage.NumLvols, Equals, 2, comment)
+		c.Assert(usage.NumSnapshots, Equals, 1, comment)
+	}
+
+	// An empty lvstore
+	usage := GetLvstoreUsage(&LvstoreInfo{UUID: "empty-uuid", Name: "empty"}, bdevLvolList, 0)
+	c.Assert(*usage, DeepEquals, LvstoreUsage{Name: "empty", UUID: "empty-uuid", TopConsumers: []LvolUsage{}})
+}
@@ -1,5 +1,9 @@
 package types
 
+import (
+	helpertypes "github.com/longhorn/go-spdk-helper/pkg/types"
+)
+
 type NvmeTransportType string
 
 const (
@@ -18,6 +22,14 @@ const (
 	NvmeAddressFamilyIntraHost = NvmeAddressFamily("intra_host")
 )
 
+// GetNvmeAddressFamily returns the address family of the IP, which is "ipv4" unless the IP is an IPv6 literal.
+func GetNvmeAddressFamily(ip string) NvmeAddressFamily {
+	if helpertypes.IsIPv6(ip) {
+		return NvmeAddressFamilyIPv6
+	}
+	return NvmeAddressFamilyIPv4
+}
+
 type NvmeMultipathBehavior string
 
 const (
@@ -74,10 +86,54 @@ type NvmeVendorSpecific struct {
 type BdevNvmeMultipathPolicy string
 
 const (
-	BdevNvmeMultipathPolicyActivePassive = "active_passive"
-	BdevNvmeMultipathPolicyActiveActive  = "active_active"
+	BdevNvmeMultipathPolicyActivePassive = BdevNvmeMultipathPolicy("active_passive")
+	BdevNvmeMultipathPolicyActiveActive  = BdevNvmeMultipathPolicy("active_active")
+)
+
+type BdevNvmeMultipathSelector string
+
+const (
+	BdevNvmeMultipathSelectorRoundRobin = BdevNvmeMultipathSelector("round_robin")
+	BdevNvmeMultipathSelectorQueueDepth = BdevNvmeMultipathSelector("queue_depth")
 )
 
+type BdevNvmeSetMultipathPolicyRequest struct {
+	Name     string                    `json:"name"`
+	Policy   BdevNvmeMultipathPolicy   `json:"policy"`
+	Selector BdevNvmeMultipathSelector `json:"selector,omitempty"`
+	RrMinIo  uint32                    `json:"rr_min_io,omitempty"`
+}
+
+type BdevNvmeSetPreferredPathRequest struct {
+	Name   string `json:"name"`
+	Cntlid uint16 `json:"cntlid"`
+}
+
+type BdevNvmeGetIoPathsRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+// BdevNvmeIoPaths is the result of bdev_nvme_get_io_paths.
+type BdevNvmeIoPaths struct {
+	PollGroups []BdevNvmeIoPathPollGroup `json:"poll_groups"`
+}
+
+type BdevNvmeIoPathPollGroup struct {
+	Thread  string           `json:"thread"`
+	IoPaths []BdevNvmeIoPath `json:"io_paths"`
+}
+
+// BdevNvmeIoPath is a path from a poll group to a namespace of a bdev NVMe.
+// Notice that the trtype and the adrfam of the transport are reported in upper case, e.g., "TCP" and "IPv4".
+type BdevNvmeIoPath struct {
+	BdevName   string          `json:"bdev_name"`
+	Cntlid     uint16          `json:"cntlid"`
+	Current    bool            `json:"current"`
+	Connected  bool            `json:"connected"`
+	Accessible bool            `json:"accessible"`
+	Transport  NvmeTransportID `json:"transport"`
+}
+
 type BdevNvmeControllerInfo struct {
 	Name   string               `json:"name"`
 	Ctrlrs []NvmeControllerInfo `json:"ctrlrs"`
@@ -109,6 +165,11 @@ type BdevNvmeAttachControllerRequest struct {
 	FastIOFailTimeoutSec int32 `json:"fast_io_fail_timeout_sec"`
 
 	Multipath string `json:"multipath,omitempty"`
+
+	Hostnqn        string `json:"hostnqn,omitempty"`
+	Psk            string `json:"psk,omitempty"`
+	DhchapKey      string `json:"dhchap_key,omitempty"`
+	DhchapCtrlrKey string `json:"dhchap_ctrlr_key,omitempty"`
 }
 
 type BdevNvmeDetachControllerRequest struct {
@@ -132,29 +193,80 @@ type BdevNvmeGetControllersRequest struct {
 	Name string `json:"name,omitempty"`
 }
 
-// type BdevNvmeControllerHealthInfo struct {
-//	ModelNumber                             string  `json:"model_number"`
-//	SerialNumber                            string  `json:"serial_number"`
-//	FirmwareRevision                        string  `json:"firmware_revision"`
-//	Traddr                                  string  `json:"traddr"`
-//	TemperatureCelsius                      uint64  `json:"temperature_celsius"`
-//	AvailableSparePercentage                uint64  `json:"available_spare_percentage"`
-//	AvailableSpareThresholdPercentage       uint64  `json:"available_spare_threshold_percentage"`
-//	PercentageUsed                          uint64  `json:"percentage_used"`
-//	DataUnitsRead                           uint128 `json:"data_units_read"`
-//	DataUnitsWritten                        uint128 `json:"data_units_written"`
-//	HostReadCommands                        uint128 `json:"host_read_commands"`
-//	HostWriteCommands                       uint128 `json:"host_write_commands"`
-//	ControllerBusyTime                      uint128 `json:"controller_busy_time"`
-//	PowerCycles                             uint128 `json:"power_cycles"`
-//	PowerOnHours                            uint128 `json:"power_on_hours"`
-//	UnsafeShutdowns                         uint128 `json:"unsafe_shutdowns"`
-//	MediaErrors                             uint128 `json:"media_errors"`
-//	NumErrLogEntries                        uint128 `json:"num_err_log_entries"`
-//	WarningTemperatureTimeMinutes           uint64  `json:"warning_temperature_time_minutes"`
-//	CriticalCompositeTemperatureTimeMinutes uint64  `json:"critical_composite_temperature_time_minutes"`
-// }
-//
-// type BdevNvmeGetControllerHealthInfoRequest struct {
-//	Name string `json:"name"`
-// }
+// BdevNvmeControllerHealthInfo is the SMART / health information log of a bdev NVMe controller.
+// The 128-bit counters are decoded as Uint128 rather than uint64 to avoid the overflow.
+type BdevNvmeControllerHealthInfo struct {
+	ModelNumber                             string  `json:"model_number"`
+	SerialNumber                            string  `json:"serial_number"`
+	FirmwareRevision                        string  `json:"firmware_revision"`
+	Traddr                                  string  `json:"traddr"`
+	CriticalWarning                         uint64  `json:"critical_warning"`
+	TemperatureCelsius                      uint64  `json:"temperature_celsius"`
+	AvailableSparePercentage                uint64  `json:"available_spare_percentage"`
+	AvailableSpareThresholdPercentage       uint64  `json:"available_spare_threshold_percentage"`
+	PercentageUsed                          uint64  `json:"percentage_used"`
+	DataUnitsRead                           Uint128 `json:"data_units_read"`
+	DataUnitsWritten                        Uint128 `json:"data_units_written"`
+	HostReadCommands                        Uint128 `json:"host_read_commands"`
+	HostWriteCommands                       Uint128 `json:"host_write_commands"`
+	ControllerBusyTime                      Uint128 `json:"controller_busy_time"`
+	PowerCycles                             Uint128 `json:"power_cycles"`
+	PowerOnHours                            Uint128 `json:"power_on_hours"`
+	UnsafeShutdowns                         Uint128 `json:"unsafe_shutdowns"`
+	MediaErrors                             Uint128 `json:"media_errors"`
+	NumErrLogEntries                        Uint128 `json:"num_err_log_entries"`
+	WarningTemperatureTimeMinutes           uint64  `json:"warning_temperature_time_minutes"`
+	CriticalCompositeTemperatureTimeMinutes uint64  `json:"critical_composite_temperature_time_minutes"`
+}
+
+type BdevNvmeGetControllerHealthInfoRequest struct {
+	Name string `json:"name"`
+}
+
+// BdevNvmeTransportStatistics is the result of bdev_nvme_get_transport_statistics.
+type BdevNvmeTransportStatistics struct {
+	PollGroups []BdevNvmePollGroupStat `json:"poll_groups"`
+}
+
+type BdevNvmePollGroupStat struct {
+	Thread     string                  `json:"thread"`
+	Transports []BdevNvmeTransportStat `json:"transports"`
+}
+
+// BdevNvmeTransportStat is the statistics of a transport in a poll group.
+// Which fields are set depends on the transport type.
+type BdevNvmeTransportStat struct {
+	Trname string `json:"trname"`
+
+	// TCP and PCIe
+	Polls             uint64 `json:"polls,omitempty"`
+	IdlePolls         uint64 `json:"idle_polls,omitempty"`
+	SubmittedRequests uint64 `json:"submitted_requests,omitempty"`
+	QueuedRequests    uint64 `json:"queued_requests,omitempty"`
+
+	// TCP
+	SocketCompletions uint64 `json:"socket_completions,omitempty"`
+	NvmeCompletions   uint64 `json:"nvme_completions,omitempty"`
+
+	// PCIe
+	Completions             uint64 `json:"completions,omitempty"`
+	CqMmioDoorbellUpdates   uint64 `json:"cq_mmio_doorbell_updates,omitempty"`
+	CqShadowDoorbellUpdates uint64 `json:"cq_shadow_doorbell_updates,omitempty"`
+	SqMmioDoorbellUpdates   uint64 `json:"sq_mmio_doorbell_updates,omitempty"`
+	SqShadowDoorbellUpdates uint64 `json:"sq_shadow_doorbell_updates,omitempty"`
+
+	// RDMA
+	Devices []BdevNvmeRdmaDeviceStat `json:"devices,omitempty"`
+}
+
+type BdevNvmeRdmaDeviceStat struct {
+	Name                string `json:"name"`
+	Polls               uint64 `json:"polls"`
+	IdlePolls           uint64 `json:"idle_polls"`
+	Completions         uint64 `json:"completions"`
+	QueuedRequests      uint64 `json:"queued_requests"`
+	TotalSendWrs        uint64 `json:"total_send_wrs"`
+	SendDoorbellUpdates uint64 `json:"send_doorbell_updates"`
+	TotalRecvWrs        uint64 `json:"total_recv_wrs"`
+	RecvDoorbellUpdates uint64 `json:"recv_doorbell_updates"`
+}
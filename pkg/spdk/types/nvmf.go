@@ -2,6 +2,23 @@ package types
 
 type NvmfCreateTransportRequest struct {
 	Trtype NvmeTransportType `json:"trtype"`
+
+	TgtName             string `json:"tgt_name,omitempty"`
+	MaxQueueDepth       uint32 `json:"max_queue_depth,omitempty"`
+	MaxIoQpairsPerCtrlr uint32 `json:"max_io_qpairs_per_ctrlr,omitempty"`
+	InCapsuleDataSize   uint32 `json:"in_capsule_data_size,omitempty"`
+	MaxIoSize           uint32 `json:"max_io_size,omitempty"`
+	IoUnitSize          uint32 `json:"io_unit_size,omitempty"`
+	MaxAqDepth          uint32 `json:"max_aq_depth,omitempty"`
+	NumSharedBuffers    uint32 `json:"num_shared_buffers,omitempty"`
+	BufCacheSize        uint32 `json:"buf_cache_size,omitempty"`
+	AbortTimeoutSec     uint32 `json:"abort_timeout_sec,omitempty"`
+	DifInsertOrStrip    bool   `json:"dif_insert_or_strip,omitempty"`
+	Zcopy               bool   `json:"zcopy,omitempty"`
+
+	// The TCP transport only
+	C2HSuccess   *bool  `json:"c2h_success,omitempty"`
+	SockPriority uint32 `json:"sock_priority,omitempty"`
 }
 
 type NvmfGetTransportRequest struct {
@@ -49,6 +66,14 @@ type NvmfGetSubsystemsRequest struct {
 	TgtName string `json:"tgt_name,omitempty"`
 }
 
+const (
+	NvmfSubsystemSubtypeNVMe      = "NVMe"
+	NvmfSubsystemSubtypeDiscovery = "Discovery"
+
+	// NvmfDiscoveryNqn is the well-known NQN of the discovery subsystem, which lists the subsystems to the hosts.
+	NvmfDiscoveryNqn = "nqn.2014-08.org.nvmexpress.discovery"
+)
+
 type NvmfSubsystem struct {
 	Nqn             string                       `json:"nqn"`
 	Subtype         string                       `json:"subtype"`
@@ -76,12 +101,42 @@ type NvmfSubsystemNamespace struct {
 	Nguid    string `json:"nguid,omitempty"`
 	Eui64    string `json:"eui64,omitempty"`
 	UUID     string `json:"uuid,omitempty"`
-	Anagrpid string `json:"anagrpid,omitempty"`
+	Anagrpid uint32 `json:"anagrpid,omitempty"`
 	PtplFile string `json:"ptpl_file,omitempty"`
 }
 
 type NvmfSubsystemHost struct {
 	Nqn string `json:"nqn"`
+
+	// Psk is the name of the keyring key used as the TLS pre-shared key
+	Psk string `json:"psk,omitempty"`
+	// DhchapKey and DhchapCtrlrKey are the names of the keyring keys used for DH-HMAC-CHAP authentication
+	DhchapKey      string `json:"dhchap_key,omitempty"`
+	DhchapCtrlrKey string `json:"dhchap_ctrlr_key,omitempty"`
+}
+
+type NvmfSubsystemAddHostRequest struct {
+	Nqn  string `json:"nqn"`
+	Host string `json:"host"`
+
+	TgtName        string `json:"tgt_name,omitempty"`
+	Psk            string `json:"psk,omitempty"`
+	DhchapKey      string `json:"dhchap_key,omitempty"`
+	DhchapCtrlrKey string `json:"dhchap_ctrlr_key,omitempty"`
+}
+
+type NvmfSubsystemRemoveHostRequest struct {
+	Nqn  string `json:"nqn"`
+	Host string `json:"host"`
+
+	TgtName string `json:"tgt_name,omitempty"`
+}
+
+type NvmfSubsystemAllowAnyHostRequest struct {
+	Nqn          string `json:"nqn"`
+	AllowAnyHost bool   `json:"allow_any_host"`
+
+	TgtName string `json:"tgt_name,omitempty"`
 }
 
 type NvmfSubsystemAddNsRequest struct {
@@ -100,7 +155,8 @@ type NvmfSubsystemAddListenerRequest struct {
 	Nqn           string                     `json:"nqn"`
 	ListenAddress NvmfSubsystemListenAddress `json:"listen_address"`
 
-	TgtName string `json:"tgt_name,omitempty"`
+	TgtName       string `json:"tgt_name,omitempty"`
+	SecureChannel bool   `json:"secure_channel,omitempty"`
 }
 
 type NvmfSubsystemRemoveListenerRequest struct {
@@ -120,8 +176,8 @@ type NvmfSubsystemListenerAnaState string
 
 const (
 	NvmfSubsystemListenerAnaStateOptimized      = "optimized"
-	NvmfSubsystemListenerAnaStateNonOptimized   = "non-optimized"
-	NvmfSubsystemListenerAnaStateInaccessible   = "Inaccessible"
+	NvmfSubsystemListenerAnaStateNonOptimized   = "non_optimized"
+	NvmfSubsystemListenerAnaStateInaccessible   = "inaccessible"
 	NvmfSubsystemListenerAnaStatePersistentLoss = "persistent-loss"
 	NvmfSubsystemListenerAnaStateChange         = "change"
 )
@@ -129,4 +185,117 @@ const (
 type NvmfSubsystemListener struct {
 	Address  NvmfSubsystemListenAddress    `json:"address"`
 	AnaState NvmfSubsystemListenerAnaState `json:"ana_state"`
+	// AnaStates is reported per ANA group by newer SPDK versions instead of AnaState
+	AnaStates []NvmfSubsystemListenerAnaGroupState `json:"ana_states,omitempty"`
+}
+
+type NvmfSubsystemListenerAnaGroupState struct {
+	AnaGroup uint32                        `json:"ana_group"`
+	AnaState NvmfSubsystemListenerAnaState `json:"ana_state"`
+}
+
+type NvmfSubsystemListenerSetAnaStateRequest struct {
+	Nqn           string                        `json:"nqn"`
+	ListenAddress NvmfSubsystemListenAddress    `json:"listen_address"`
+	AnaState      NvmfSubsystemListenerAnaState `json:"ana_state"`
+
+	TgtName  string `json:"tgt_name,omitempty"`
+	Anagrpid uint32 `json:"anagrpid,omitempty"`
+}
+
+type NvmfSubsystemPauseRequest struct {
+	Nqn string `json:"nqn"`
+
+	Nsid    uint32 `json:"nsid,omitempty"`
+	TgtName string `json:"tgt_name,omitempty"`
+}
+
+type NvmfSubsystemResumeRequest struct {
+	Nqn string `json:"nqn"`
+
+	TgtName string `json:"tgt_name,omitempty"`
+}
+
+type NvmfSubsystemGetControllersRequest struct {
+	Nqn string `json:"nqn"`
+
+	TgtName string `json:"tgt_name,omitempty"`
+}
+
+type NvmfSubsystemController struct {
+	Cntlid      uint16 `json:"cntlid"`
+	Hostnqn     string `json:"hostnqn"`
+	Hostid      string `json:"hostid"`
+	NumIoQpairs uint32 `json:"num_io_qpairs"`
+}
+
+type NvmfSubsystemGetQpairsRequest struct {
+	Nqn string `json:"nqn"`
+
+	TgtName string `json:"tgt_name,omitempty"`
+}
+
+type NvmfSubsystemQpair struct {
+	Cntlid        uint16                     `json:"cntlid"`
+	Qid           uint16                     `json:"qid"`
+	State         string                     `json:"state"`
+	Thread        string                     `json:"thread"`
+	Hostnqn       string                     `json:"hostnqn,omitempty"`
+	ListenAddress NvmfSubsystemListenAddress `json:"listen_address"`
+	// PeerAddress is the address of the host. It is reported by newer SPDK versions only
+	PeerAddress *NvmfSubsystemListenAddress `json:"peer_address,omitempty"`
+}
+
+type NvmfGetStatsRequest struct {
+	TgtName string `json:"tgt_name,omitempty"`
+}
+
+type NvmfStats struct {
+	TickRate   uint64              `json:"tick_rate"`
+	PollGroups []NvmfPollGroupStat `json:"poll_groups"`
+}
+
+type NvmfPollGroupStat struct {
+	Name               string `json:"name"`
+	AdminQpairs        uint64 `json:"admin_qpairs"`
+	IoQpairs           uint64 `json:"io_qpairs"`
+	CurrentAdminQpairs uint64 `json:"current_admin_qpairs"`
+	CurrentIoQpairs    uint64 `json:"current_io_qpairs"`
+	PendingBdevIo      uint64 `json:"pending_bdev_io"`
+	CompletedNvmeIo    uint64 `json:"completed_nvme_io"`
+
+	Transports []NvmfPollGroupTransportStat `json:"transports"`
+}
+
+type NvmfPollGroupTransportStat struct {
+	Trtype NvmeTransportType `json:"trtype"`
+	// PendingDataBuffer is reported by the TCP transport only
+	PendingDataBuffer uint64 `json:"pending_data_buffer,omitempty"`
+}
+
+type NvmfDiscoveryReferral struct {
+	Address NvmfSubsystemListenAddress `json:"address"`
+
+	// Subnqn is the NQN of the subsystem referred to. The referral is to a discovery service if not specified
+	Subnqn        string `json:"subnqn,omitempty"`
+	SecureChannel bool   `json:"secure_channel,omitempty"`
+}
+
+type NvmfDiscoveryAddReferralRequest struct {
+	Address NvmfSubsystemListenAddress `json:"address"`
+
+	Subnqn        string `json:"subnqn,omitempty"`
+	SecureChannel bool   `json:"secure_channel,omitempty"`
+	TgtName       string `json:"tgt_name,omitempty"`
+}
+
+type NvmfDiscoveryRemoveReferralRequest struct {
+	Address NvmfSubsystemListenAddress `json:"address"`
+
+	Subnqn  string `json:"subnqn,omitempty"`
+	TgtName string `json:"tgt_name,omitempty"`
+}
+
+type NvmfDiscoveryGetReferralsRequest struct {
+	TgtName string `json:"tgt_name,omitempty"`
 }
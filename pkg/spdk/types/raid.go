@@ -22,6 +22,25 @@ type BdevRaidInfo struct {
 	NumBaseBdevsOperational uint8         `json:"num_base_bdevs_operational,omitempty"`
 	BaseBdevsList           []BaseBdev    `json:"base_bdevs_list"`
 	Superblock              bool          `json:"superblock"`
+	// Process is set only while a background process, e.g., a rebuild, is running on the RAID bdev.
+	Process *BdevRaidProcessInfo `json:"process,omitempty"`
+}
+
+type BdevRaidProcessType string
+
+const (
+	BdevRaidProcessTypeRebuild = BdevRaidProcessType("rebuild")
+)
+
+type BdevRaidProcessInfo struct {
+	Type     BdevRaidProcessType     `json:"type"`
+	Target   string                  `json:"target"`
+	Progress BdevRaidProcessProgress `json:"progress"`
+}
+
+type BdevRaidProcessProgress struct {
+	Blocks  uint64  `json:"blocks"`
+	Percent float64 `json:"percent"`
 }
 
 type BaseBdev struct {
@@ -37,6 +56,9 @@ type BdevRaidCreateRequest struct {
 	RaidLevel   BdevRaidLevel `json:"raid_level"`
 	StripSizeKb uint32        `json:"strip_size_kb"`
 	BaseBdevs   []string      `json:"base_bdevs"`
+	UUID        string        `json:"uuid,omitempty"`
+	Superblock  bool          `json:"superblock,omitempty"`
+	DeltaBitmap bool          `json:"delta_bitmap,omitempty"`
 }
 
 type BdevRaidDeleteRequest struct {
@@ -64,3 +86,8 @@ type BdevRaidGrowBaseBdevRequest struct {
 	RaidName string `json:"raid_name"`
 	BaseName string `json:"base_name"`
 }
+
+type BdevRaidAddBaseBdevRequest struct {
+	BaseBdev string `json:"base_bdev"`
+	RaidBdev string `json:"raid_bdev"`
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Uint128 is an unsigned 128-bit integer, e.g., the NVMe SMART counters reported by SPDK.
+// It is encoded as a JSON number and can be decoded from a JSON number or a decimal string.
+type Uint128 struct {
+	Hi uint64
+	Lo uint64
+}
+
+// BigInt returns the value as a big.Int.
+func (u Uint128) BigInt() *big.Int {
+	hi := new(big.Int).SetUint64(u.Hi)
+	return hi.Lsh(hi, 64).Or(hi, new(big.Int).SetUint64(u.Lo))
+}
+
+// Uint64 returns the value as an uint64, saturating at math.MaxUint64.
+func (u Uint128) Uint64() uint64 {
+	if u.Hi != 0 {
+		return math.MaxUint64
+	}
+	return u.Lo
+}
+
+func (u Uint128) String() string {
+	return u.BigInt().String()
+}
+
+func (u Uint128) MarshalJSON() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *Uint128) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	data = bytes.Trim(data, `"`)
+
+	value, ok := new(big.Int).SetString(string(data), 10)
+	if !ok {
+		return fmt.Errorf("invalid uint128 value %s", data)
+	}
+	if value.Sign() < 0 || value.BitLen() > 128 {
+		return fmt.Errorf("uint128 value %s is out of range", data)
+	}
+
+	mask := new(big.Int).SetUint64(math.MaxUint64)
+	u.Lo = new(big.Int).And(value, mask).Uint64()
+	u.Hi = new(big.Int).Rsh(value, 64).Uint64()
+	return nil
+}
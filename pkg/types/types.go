@@ -1,7 +1,10 @@
 package types
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 )
@@ -12,7 +15,11 @@ const (
 	DefaultJSONServerNetwork    = "unix"
 	DefaultUnixDomainSocketPath = "/var/tmp/spdk.sock"
 
-	LocalIP = "127.0.0.1"
+	LocalIP   = "127.0.0.1"
+	LocalIPv6 = "::1"
+
+	// NvmfSerialNumberLength is the max length of the serial number of an NVMe-oF subsystem.
+	NvmfSerialNumberLength = 20
 
 	MiB = 1 << 20
 
@@ -67,6 +74,35 @@ func GetNQN(name string) string {
 	return fmt.Sprintf("%s:%s", NQNPrefix, name)
 }
 
+// GetSerialNumber returns the serial number of the NVMe-oF subsystem with the NQN.
+// It is deterministic so that the host sees the same /dev/disk/by-id name whenever the volume is exposed.
+func GetSerialNumber(nqn string) string {
+	sum := sha256.Sum256([]byte(nqn))
+	return hex.EncodeToString(sum[:])[:NvmfSerialNumberLength]
+}
+
+// NormalizeIP returns the canonical form of an IP literal without brackets, e.g., "::1" for "[0:0:0:0:0:0:0:1]",
+// so that the IPs reported by different tools can be compared and passed to SPDK and nvme-cli.
+// The zone of a link-local IPv6 address is kept. Anything else, e.g., a host name, is returned as is.
+func NormalizeIP(ip string) string {
+	host, zone, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]"), "%")
+	parsed := net.ParseIP(host)
+	if parsed == nil {
+		return ip
+	}
+	if zone != "" {
+		return parsed.String() + "%" + zone
+	}
+	return parsed.String()
+}
+
+// IsIPv6 returns true if the IP is an IPv6 literal, bracketed or not.
+func IsIPv6(ip string) bool {
+	host, _, _ := strings.Cut(NormalizeIP(ip), "%")
+	parsed := net.ParseIP(host)
+	return parsed != nil && parsed.To4() == nil
+}
+
 type DiskStatus struct {
 	Bdf          string
 	Type         string
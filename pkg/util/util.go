@@ -3,8 +3,12 @@ package util
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 )
 
+const progressBarWidth = 40
+
 func PrintObject(v interface{}) error {
 	jsonOutput, err := json.MarshalIndent(v, "", "\t")
 	if err != nil {
@@ -14,3 +18,18 @@ func PrintObject(v interface{}) error {
 	fmt.Println(string(jsonOutput))
 	return nil
 }
+
+// PrintProgressBar redraws the progress bar on the current line of stderr, followed by the detail.
+// The caller should print a newline to stderr once the progress ends.
+func PrintProgressBar(percentage float64, detail string) {
+	filled := int(percentage * progressBarWidth / 100)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	if filled < 0 {
+		filled = 0
+	}
+
+	fmt.Fprintf(os.Stderr, "\r[%s%s] %5.1f%% %s   ",
+		strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled), percentage, detail)
+}